@@ -0,0 +1,522 @@
+// Package asm assembles CHIP-8 programs written with the mnemonics of chip8.OpInfo, the syntax
+// chip8.Disassembly writes, so a disassembly assembles back into the same ROM.
+//
+//	; comments run to the end of the line
+//	include "font.asm"        ; assemble another file here, found relative to this one
+//	SPEED = 4                 ; a constant
+//	start:                    ; a label, the address of what follows
+//		SET v0,SPEED
+//		ADR ball
+//		DRW v0,v1,ball_end-ball
+//		JMP start
+//	ball:
+//		db 0x60, 0xF0, 0xF0, 0x60
+//	ball_end:
+//		dw 0x1234, start          ; big-endian words
+//
+// Mnemonics and the registers v0-vF, I, DT and ST are not case sensitive; labels and constants
+// are. Numbers are decimal, 0x hexadecimal or 0b binary, and may be added and subtracted.
+package asm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+// Error is a problem at a position in the source
+type Error struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
+
+// position is a place in the source, for errors
+type position struct {
+	file   string
+	line   int
+	column int
+}
+
+func (p position) errorf(format string, args ...interface{}) *Error {
+	return &Error{File: p.file, Line: p.line, Column: p.column, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p position) at(column int) position {
+	p.column = column
+	return p
+}
+
+// statement is an instruction or data directive, placed at an address by the first pass
+type statement struct {
+	pos  position
+	addr int
+
+	//directive is db or dw, or empty for an instruction
+	directive string
+	op        chip8.Op
+
+	operands []operand
+}
+
+// operand is a comma separated part of a statement
+type operand struct {
+	pos    position
+	tokens []token
+}
+
+// symbol is a label or constant
+type symbol struct {
+	pos position
+
+	//a label's address, or a constant's value once evaluated
+	value    int
+	resolved bool
+
+	//a constant's expression
+	expr      operand
+	resolving bool
+}
+
+type assembler struct {
+	statements []*statement
+	symbols    map[string]*symbol
+
+	//address of the next statement
+	addr int
+
+	//files being assembled, innermost last, to reject include cycles
+	including []string
+}
+
+// Assemble assembles source read from the file name, returning the program to load at
+// chip8.ProgramAddress. Errors are of type *Error.
+func Assemble(name string, source []byte) ([]byte, error) {
+	a := &assembler{symbols: map[string]*symbol{}, addr: chip8.ProgramAddress}
+	if err := a.parseFile(name, source); err != nil {
+		return nil, err
+	}
+	program := make([]byte, 0, a.addr-chip8.ProgramAddress)
+	for _, s := range a.statements {
+		code, err := a.encode(s)
+		if err != nil {
+			return nil, err
+		}
+		program = append(program, code...)
+	}
+	return program, nil
+}
+
+// AssembleFile assembles the source file at path
+func AssembleFile(path string) ([]byte, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Assemble(path, source)
+}
+
+// parseFile is the first pass over a file: it places each statement and label, and picks the
+// operation of each instruction from its operands
+func (a *assembler) parseFile(name string, source []byte) error {
+	a.including = append(a.including, name)
+	defer func() { a.including = a.including[:len(a.including)-1] }()
+
+	for n, line := range strings.Split(string(source), "\n") {
+		pos := position{file: name, line: n + 1, column: 1}
+		tokens, lexErr := lex(strings.TrimSuffix(line, "\r"))
+		if lexErr != nil {
+			return pos.at(lexErr.column).errorf("%s", lexErr.msg)
+		}
+
+		//a label may share its line with a statement
+		if len(tokens) >= 2 && tokens[0].kind == tokenIdent && tokens[1].text == ":" {
+			if err := a.define(tokens[0], pos, &symbol{value: a.addr, resolved: true}); err != nil {
+				return err
+			}
+			tokens = tokens[2:]
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		if tokens[0].kind != tokenIdent {
+			return pos.at(tokens[0].column).errorf("expected an instruction, found %s", tokens[0].text)
+		}
+		pos.column = tokens[0].column
+
+		if len(tokens) >= 2 && tokens[1].text == "=" {
+			expr := operand{pos: pos.at(tokens[1].column), tokens: tokens[2:]}
+			if len(expr.tokens) == 0 {
+				return expr.pos.errorf("missing value of %s", tokens[0].text)
+			}
+			expr.pos.column = expr.tokens[0].column
+			if err := a.define(tokens[0], pos, &symbol{expr: expr}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		operands, err := splitOperands(pos, tokens[1:])
+		if err != nil {
+			return err
+		}
+		if err := a.parseStatement(pos, strings.ToLower(tokens[0].text), operands); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseStatement places a directive or instruction
+func (a *assembler) parseStatement(pos position, mnemonic string, operands []operand) error {
+	switch mnemonic {
+	case "include":
+		if len(operands) != 1 || len(operands[0].tokens) != 1 || operands[0].tokens[0].kind != tokenString {
+			return pos.errorf("include takes a quoted file name")
+		}
+		return a.include(operands[0].pos, operands[0].tokens[0].string)
+	case "db", "dw":
+		if len(operands) == 0 {
+			return pos.errorf("%s needs at least one value", mnemonic)
+		}
+		a.statements = append(a.statements, &statement{pos: pos, addr: a.addr, directive: mnemonic, operands: operands})
+		for _, o := range operands {
+			switch {
+			case mnemonic == "dw":
+				a.addr += 2
+			case len(o.tokens) == 1 && o.tokens[0].kind == tokenString:
+				a.addr += len(o.tokens[0].string)
+			default:
+				a.addr++
+			}
+		}
+		return nil
+	}
+
+	op, err := matchOp(pos, mnemonic, operands)
+	if err != nil {
+		return err
+	}
+	a.statements = append(a.statements, &statement{pos: pos, addr: a.addr, op: op, operands: operands})
+	a.addr += chip8.WordLength
+	if op == chip8.OpSetILong {
+		a.addr += chip8.WordLength
+	}
+	return nil
+}
+
+// include assembles another file in place, relative to the including file
+func (a *assembler) include(pos position, name string) error {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(pos.file), name)
+	}
+	for _, file := range a.including {
+		if file == name {
+			return pos.errorf("%s includes itself", name)
+		}
+	}
+	source, err := os.ReadFile(name)
+	if err != nil {
+		return pos.errorf("%s", err)
+	}
+	return a.parseFile(name, source)
+}
+
+// define names a label or constant
+func (a *assembler) define(name token, pos position, s *symbol) error {
+	pos.column = name.column
+	if reserved(name.text) {
+		return pos.errorf("%s is a register and cannot be redefined", name.text)
+	}
+	if previous, ok := a.symbols[name.text]; ok {
+		return pos.errorf("%s redefined, first defined at %s:%d", name.text, previous.pos.file, previous.pos.line)
+	}
+	s.pos = pos
+	a.symbols[name.text] = s
+	return nil
+}
+
+// splitOperands splits the tokens after a mnemonic at commas
+func splitOperands(pos position, tokens []token) ([]operand, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	var operands []operand
+	var current operand
+	for _, t := range tokens {
+		if t.text != "," {
+			if len(current.tokens) == 0 {
+				current.pos = pos.at(t.column)
+			}
+			current.tokens = append(current.tokens, t)
+			continue
+		}
+		if len(current.tokens) == 0 {
+			return nil, pos.at(t.column).errorf("missing operand before ,")
+		}
+		operands = append(operands, current)
+		current = operand{pos: pos.at(t.column + 1)}
+	}
+	if len(current.tokens) == 0 {
+		return nil, current.pos.errorf("missing operand after ,")
+	}
+	return append(operands, current), nil
+}
+
+// register returns the register an operand names, if it is one
+func (o operand) register() (byte, bool) {
+	if len(o.tokens) != 1 || o.tokens[0].kind != tokenIdent {
+		return 0, false
+	}
+	return parseRegister(o.tokens[0].text)
+}
+
+func parseRegister(name string) (byte, bool) {
+	if len(name) != 2 || (name[0] != 'v' && name[0] != 'V') {
+		return 0, false
+	}
+	value, err := parseNumber("0x" + name[1:])
+	return byte(value), err == nil
+}
+
+// keyword returns the upper case name of an operand that is written literally in an operand
+// template, such as I or DT
+func (o operand) keyword() (string, bool) {
+	if len(o.tokens) != 1 || o.tokens[0].kind != tokenIdent {
+		return "", false
+	}
+	name := strings.ToUpper(o.tokens[0].text)
+	return name, isKeyword(name)
+}
+
+func isKeyword(name string) bool {
+	return name == "I" || name == "DT" || name == "ST"
+}
+
+// reserved reports whether a name cannot be used for a symbol
+func reserved(name string) bool {
+	_, ok := parseRegister(name)
+	return ok || isKeyword(strings.ToUpper(name))
+}
+
+// matchOp finds the operation whose mnemonic and operand template fit an instruction
+func matchOp(pos position, mnemonic string, operands []operand) (chip8.Op, error) {
+	var templates []string
+	for _, op := range chip8.Ops() {
+		info := op.Info()
+		if !strings.EqualFold(info.Mnemonic, mnemonic) {
+			continue
+		}
+		templates = append(templates, info.Operands)
+		if operandsFit(info.Operands, operands) {
+			return op, nil
+		}
+	}
+	if len(templates) == 0 {
+		return chip8.OpInvalid, pos.errorf("unknown instruction %s", mnemonic)
+	}
+	for i, template := range templates {
+		if template == "" {
+			templates[i] = "no operands"
+		}
+	}
+	return chip8.OpInvalid, pos.errorf("%s takes %s", strings.ToUpper(mnemonic), strings.Join(templates, " or "))
+}
+
+// operandsFit reports whether operands have the kinds an operand template asks for
+func operandsFit(template string, operands []operand) bool {
+	var fields []string
+	if template != "" {
+		fields = strings.Split(template, ",")
+	}
+	if len(fields) != len(operands) {
+		return false
+	}
+	for i, field := range fields {
+		_, isRegister := operands[i].register()
+		keyword, isKeyword := operands[i].keyword()
+		switch field {
+		case "vX", "vY":
+			if !isRegister {
+				return false
+			}
+		case "X", "N", "NN", "NNN", "NNNN":
+			if isRegister || isKeyword {
+				return false
+			}
+		default:
+			if keyword != field {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// encode is the second pass over a statement, once every label is placed
+func (a *assembler) encode(s *statement) ([]byte, error) {
+	if s.directive != "" {
+		return a.encodeData(s)
+	}
+
+	inst := chip8.Instruction{Op: s.op}
+	info := s.op.Info()
+	if info.Operands != "" {
+		for i, field := range strings.Split(info.Operands, ",") {
+			o := s.operands[i]
+			switch field {
+			case "vX":
+				inst.X, _ = o.register()
+				continue
+			case "vY":
+				inst.Y, _ = o.register()
+				continue
+			case "X", "N", "NN", "NNN", "NNNN":
+			default:
+				continue
+			}
+
+			value, err := a.evaluate(o)
+			if err != nil {
+				return nil, err
+			}
+			switch field {
+			case "X", "N":
+				if value < 0 || value > 0xF {
+					return nil, o.pos.errorf("%d does not fit in 4 bits", value)
+				}
+				if field == "X" {
+					inst.X = byte(value)
+				} else {
+					inst.N = byte(value)
+				}
+			case "NN":
+				if value < -0x80 || value > 0xFF {
+					return nil, o.pos.errorf("%d does not fit in a byte", value)
+				}
+				inst.NN = byte(value)
+			case "NNN":
+				if value < 0 || value > 0xFFF {
+					return nil, o.pos.errorf("%#x does not fit in 12 bits", value)
+				}
+				inst.NNN = uint16(value)
+			case "NNNN":
+				if value < 0 || value > 0xFFFF {
+					return nil, o.pos.errorf("%#x does not fit in 16 bits", value)
+				}
+				inst.NNNN = uint16(value)
+			}
+		}
+	}
+
+	opCode := inst.Encode()
+	code := []byte{byte(opCode >> 8), byte(opCode)}
+	if s.op == chip8.OpSetILong {
+		code = append(code, byte(inst.NNNN>>8), byte(inst.NNNN))
+	}
+	return code, nil
+}
+
+// encodeData writes the values of a db or dw directive
+func (a *assembler) encodeData(s *statement) ([]byte, error) {
+	var data []byte
+	for _, o := range s.operands {
+		if s.directive == "db" && len(o.tokens) == 1 && o.tokens[0].kind == tokenString {
+			data = append(data, o.tokens[0].string...)
+			continue
+		}
+		value, err := a.evaluate(o)
+		if err != nil {
+			return nil, err
+		}
+		if s.directive == "dw" {
+			if value < -0x8000 || value > 0xFFFF {
+				return nil, o.pos.errorf("%d does not fit in a word", value)
+			}
+			data = append(data, byte(value>>8), byte(value))
+			continue
+		}
+		if value < -0x80 || value > 0xFF {
+			return nil, o.pos.errorf("%d does not fit in a byte", value)
+		}
+		data = append(data, byte(value))
+	}
+	return data, nil
+}
+
+// evaluate computes an expression of numbers and symbols joined by + and -
+func (a *assembler) evaluate(o operand) (int, error) {
+	total := 0
+	sign := 1
+	expectTerm := true
+	for _, t := range o.tokens {
+		pos := o.pos.at(t.column)
+		if !expectTerm {
+			switch t.text {
+			case "+":
+				sign = 1
+			case "-":
+				sign = -1
+			default:
+				return 0, pos.errorf("expected + or -, found %s", t.text)
+			}
+			expectTerm = true
+			continue
+		}
+
+		switch t.kind {
+		case tokenNumber:
+			total += sign * t.value
+		case tokenIdent:
+			value, err := a.lookup(pos, t.text)
+			if err != nil {
+				return 0, err
+			}
+			total += sign * value
+		default:
+			if t.text == "-" {
+				sign = -sign
+				continue
+			}
+			return 0, pos.errorf("expected a number, found %s", t.text)
+		}
+		expectTerm = false
+	}
+	if expectTerm {
+		return 0, o.pos.errorf("incomplete expression")
+	}
+	return total, nil
+}
+
+// lookup finds the value of a symbol, evaluating constants on first use
+func (a *assembler) lookup(pos position, name string) (int, error) {
+	s, ok := a.symbols[name]
+	if !ok {
+		if reserved(name) {
+			return 0, pos.errorf("%s is not allowed here", name)
+		}
+		return 0, pos.errorf("undefined: %s", name)
+	}
+	if s.resolved {
+		return s.value, nil
+	}
+	if s.resolving {
+		return 0, pos.errorf("%s is defined in terms of itself", name)
+	}
+	s.resolving = true
+	value, err := a.evaluate(s.expr)
+	s.resolving = false
+	if err != nil {
+		return 0, err
+	}
+	s.value, s.resolved = value, true
+	return value, nil
+}
@@ -0,0 +1,136 @@
+package asm_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+	"github.com/alisdairrankine/chip8/asm"
+)
+
+func TestAssembleEveryOp(t *testing.T) {
+	for _, op := range chip8.Ops() {
+		//fill every operand field, so a field left out of the encoding shows up
+		opCode := op.Info().Pattern | 0x0AB5&^op.Info().Mask()
+		code := []byte{byte(opCode >> 8), byte(opCode), 0x12, 0x34}
+		inst := chip8.DecodeAt(code, 0, chip8.PlatformXOCHIP)
+		if inst.Op != op {
+			t.Fatalf("%s: test opcode %X decodes as %s", op.Info().Mnemonic, code[:2], inst.Mnemonic)
+		}
+		program, err := asm.Assemble("op.asm", []byte(inst.String()))
+		if err != nil {
+			t.Errorf("%s: %s", inst, err)
+			continue
+		}
+		if !bytes.Equal(program, code[:inst.Length]) {
+			t.Errorf("%s: assembled %X, expected %X", inst, program, code[:inst.Length])
+		}
+	}
+}
+
+func TestAssembleDisassembly(t *testing.T) {
+	rom, err := chip8.LoadROMFile("../ROMs/TETRIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := asm.Assemble("TETRIS.asm", []byte(chip8.DisassembleProgram(rom)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(program, rom) {
+		t.Error("disassembly did not assemble back into the ROM")
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	source := `
+SPEED = END - START + 1  ; constants may refer to labels defined later
+START:	SET v0,SPEED
+	set VA , -1
+	JMP end_loop
+	db 1, 0b101, "hi"
+end_loop: ADR START+2
+	ADL START
+	dw 0x1234, START
+END:
+`
+	expected := []byte{
+		0x60, 0x15, //SET v0,0x15
+		0x6A, 0xFF, //SET vA,0xff
+		0x12, 0x0A, //JMP 0x20A
+		0x01, 0x05, 'h', 'i',
+		0xA2, 0x02, //ADR 0x202
+		0xF0, 0x00, 0x02, 0x00, //ADL 0x200
+		0x12, 0x34, 0x02, 0x00,
+	}
+	program, err := asm.Assemble("test.asm", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(program, expected) {
+		t.Errorf("assembled %X, expected %X", program, expected)
+	}
+}
+
+func TestAssembleInclude(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, source string) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.asm", "JMP sprite\ninclude \"lib/sprites.asm\"\n")
+	write("lib/sprites.asm", "sprite: db 0xFF\ninclude \"loop.asm\"\n")
+	write("lib/loop.asm", "include \"sprites.asm\"\n")
+
+	_, err := asm.AssembleFile(filepath.Join(dir, "main.asm"))
+	var asmErr *asm.Error
+	if !errors.As(err, &asmErr) || asmErr.File != filepath.Join(dir, "lib/loop.asm") || asmErr.Line != 1 {
+		t.Errorf("expected an include cycle in loop.asm, got %v", err)
+	}
+
+	write("lib/loop.asm", "CLS\n")
+	program, err := asm.AssembleFile(filepath.Join(dir, "main.asm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{0x12, 0x02, 0xFF, 0x00, 0xE0}; !bytes.Equal(program, expected) {
+		t.Errorf("assembled %X, expected %X", program, expected)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	for _, test := range []struct {
+		source string
+		line   int
+		column int
+	}{
+		{"CLS\nFOO v1", 2, 1},
+		{"  SET v1,missing", 1, 10},
+		{"SET v1,DT,5", 1, 1},
+		{"DRW v1,v2,16", 1, 11},
+		{"JMP 0x1000", 1, 5},
+		{"db 1,,2", 1, 6},
+		{"a: CLS\na: CLS", 2, 1},
+		{"v3: CLS", 1, 1},
+		{"x = y\ny = x\nSET v0,x", 2, 5},
+		{"db \"open", 1, 4},
+		{"CLS ?", 1, 5},
+	} {
+		_, err := asm.Assemble("test.asm", []byte(test.source))
+		var asmErr *asm.Error
+		if !errors.As(err, &asmErr) {
+			t.Errorf("%q: expected an error, got %v", test.source, err)
+			continue
+		}
+		if asmErr.Line != test.line || asmErr.Column != test.column {
+			t.Errorf("%q: error %q at %d:%d, expected %d:%d", test.source, asmErr, asmErr.Line, asmErr.Column, test.line, test.column)
+		}
+	}
+}
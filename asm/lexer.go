@@ -0,0 +1,103 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenPunct
+)
+
+// token is a word of a source line
+type token struct {
+	kind   tokenKind
+	text   string
+	column int
+
+	//value of a number, or the contents of a string
+	value  int
+	string string
+}
+
+// lex splits a source line into tokens, stopping at a ; comment
+func lex(line string) ([]token, *lexError) {
+	var tokens []token
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case r == ';':
+			return tokens, nil
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+			if i == len(runes) {
+				return nil, &lexError{start + 1, "unterminated string"}
+			}
+			i++
+			text := string(runes[start:i])
+			s, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, &lexError{start + 1, fmt.Sprintf("bad string %s", text)}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, column: start + 1, string: s})
+		case isIdentRune(r):
+			for i < len(runes) && isIdentRune(runes[i]) {
+				i++
+			}
+			text := string(runes[start:i])
+			if !unicode.IsDigit(r) {
+				tokens = append(tokens, token{kind: tokenIdent, text: text, column: start + 1})
+				continue
+			}
+			value, err := parseNumber(text)
+			if err != nil {
+				return nil, &lexError{start + 1, fmt.Sprintf("bad number %s", text)}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, column: start + 1, value: value})
+		case strings.ContainsRune(",:=+-", r):
+			i++
+			tokens = append(tokens, token{kind: tokenPunct, text: string(r), column: start + 1})
+		default:
+			return nil, &lexError{start + 1, fmt.Sprintf("unexpected %q", r)}
+		}
+	}
+	return tokens, nil
+}
+
+// lexError is a lexing error at a column of the line
+type lexError struct {
+	column int
+	msg    string
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// parseNumber parses decimal, 0x hexadecimal and 0b binary numbers
+func parseNumber(text string) (int, error) {
+	lower := strings.ToLower(text)
+	base := 10
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		base, lower = 16, lower[2:]
+	case strings.HasPrefix(lower, "0b"):
+		base, lower = 2, lower[2:]
+	}
+	value, err := strconv.ParseInt(lower, base, 32)
+	return int(value), err
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alisdairrankine/chip8/asm"
+)
+
+func asmCommand(args []string) int {
+	flags := flag.NewFlagSet("asm", flag.ContinueOnError)
+	out := flags.String("o", "", "file to write the ROM to (default: the source file with a .ch8 extension)")
+	path, code, ok := parseFlags(flags, args, "source")
+	if !ok {
+		return code
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(path, filepath.Ext(path)) + ".ch8"
+	}
+
+	rom, err := asm.AssembleFile(path)
+	if err != nil {
+		log.Print(err)
+		return exitROM
+	}
+	if err := os.WriteFile(*out, rom, 0644); err != nil {
+		log.Printf("could not write ROM: %s", err)
+		return exitHost
+	}
+	return exitOK
+}
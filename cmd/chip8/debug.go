@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func debugCommand(args []string) int {
+	return runROM("debug", args)
+}
+
+// parseBreakpoints reads a -break list of hex addresses
+func parseBreakpoints(list string) ([]uint16, bool) {
+	var breaks []uint16
+	if list == "" {
+		return breaks, true
+	}
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		addr, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(field), "0x"), 16, 16)
+		if err != nil {
+			log.Printf("bad breakpoint: %s", field)
+			return nil, false
+		}
+		breaks = append(breaks, uint16(addr))
+	}
+	return breaks, true
+}
+
+// startDebugger attaches a debugger taking commands from the terminal, paused before the
+// first instruction for the debug command
+func startDebugger(cpu *chip8.CPU, rewind *chip8.Rewind, opts runOptions) {
+	debugger := chip8.NewDebugger(cpu, os.Stdin, os.Stdout)
+	debugger.Rewind = rewind
+	for _, addr := range opts.breaks {
+		debugger.Break(addr)
+	}
+	if opts.debug {
+		debugger.Interrupt()
+	}
+}
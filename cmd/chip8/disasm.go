@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func disasmCommand(args []string) int {
+	flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	platform := flags.String("platform", "auto", "instruction set: auto, chip8, schip, xochip")
+	syntax := flags.String("syntax", "native", "assembly syntax: native, octo")
+	path, code, ok := parseFlags(flags, args, "rom")
+	if !ok {
+		return code
+	}
+	s, ok := chip8.LookupSyntax(*syntax)
+	if !ok {
+		log.Printf("unknown syntax: %s", *syntax)
+		return exitUsage
+	}
+	p := chip8.PlatformCHIP8
+	if *platform != "auto" {
+		p, ok = chip8.LookupPlatform(*platform)
+		if !ok {
+			log.Printf("unknown platform: %s", *platform)
+			return exitUsage
+		}
+	}
+	rom, ok := loadROM(path)
+	if !ok {
+		return exitROM
+	}
+	if *platform == "auto" {
+		p = chip8.DetectPlatform(rom)
+	}
+
+	fmt.Print(chip8.Disassemble(rom, p).Format(s))
+	return exitOK
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/sha1"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func infoCommand(args []string) int {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	path, code, ok := parseFlags(flags, args, "rom")
+	if !ok {
+		return code
+	}
+	rom, ok := loadROM(path)
+	if !ok {
+		return exitROM
+	}
+
+	fmt.Printf("file:     %s\n", path)
+	fmt.Printf("size:     %d bytes\n", len(rom))
+	fmt.Printf("sha1:     %x\n", sha1.Sum(rom))
+	fmt.Printf("platform: %s\n", chip8.DetectPlatform(rom))
+	fmt.Printf("opcodes:  %s\n", opcodeUsage(rom))
+	return exitOK
+}
+
+// opcodeUsage counts the mnemonics of the aligned words of rom, most used first
+func opcodeUsage(rom []byte) string {
+	counts := map[string]int{}
+	for i := 0; i+1 < len(rom); i += chip8.WordLength {
+		inst := chip8.Decode(uint16(rom[i])<<8|uint16(rom[i+1]), chip8.PlatformXOCHIP)
+		name := inst.Mnemonic
+		if inst.Op == chip8.OpInvalid {
+			//data, or an opcode no platform defines
+			name = "invalid"
+		}
+		counts[name]++
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	used := make([]string, len(names))
+	for i, name := range names {
+		used[i] = fmt.Sprintf("%s %d", name, counts[name])
+	}
+	return strings.Join(used, ", ")
+}
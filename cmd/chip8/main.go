@@ -0,0 +1,107 @@
+// Command chip8 runs, disassembles, assembles and inspects CHIP-8, SUPER-CHIP and XO-CHIP ROMs.
+//
+// Exit codes:
+//
+//	0 success
+//	1 the emulated program faulted
+//	2 bad command line
+//	3 the ROM could not be loaded, or its source assembled
+//	4 the host could not provide a display, audio or output file
+//	5 a movie played back differently from its recording
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+const (
+	exitOK     = 0
+	exitFault  = 1
+	exitUsage  = 2
+	exitROM    = 3
+	exitHost   = 4
+	exitDesync = 5
+)
+
+var commands = []struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}{
+	{"run", "run a ROM", runCommand},
+	{"debug", "run a ROM in the debugger, paused at its first instruction", debugCommand},
+	{"disasm", "print the disassembly of a ROM", disasmCommand},
+	{"info", "report the size, hash, platform and opcodes of a ROM", infoCommand},
+	{"asm", "assemble a ROM from source", asmCommand},
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("chip8: ")
+	os.Exit(dispatch(os.Args[1:]))
+}
+
+func dispatch(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitUsage
+	}
+	for _, command := range commands {
+		if command.name == args[0] {
+			return command.run(args[1:])
+		}
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		usage()
+		return exitOK
+	}
+	log.Printf("unknown command %q", args[0])
+	usage()
+	return exitUsage
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: chip8 <command> [flags] file")
+	fmt.Fprintln(os.Stderr)
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run \"chip8 <command> -h\" for the flags of a command.")
+}
+
+// parseFlags parses the flags of a command that takes a single file argument, named arg in
+// the usage. It returns the file path, or an exit code when the command should stop.
+func parseFlags(flags *flag.FlagSet, args []string, arg string) (string, int, bool) {
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: chip8 %s [flags] %s\n", flags.Name(), arg)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return "", exitOK, false
+		}
+		return "", exitUsage, false
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return "", exitUsage, false
+	}
+	return flags.Arg(0), exitOK, true
+}
+
+// loadROM reads the ROM at path, logging why it could not be loaded
+func loadROM(path string) ([]byte, bool) {
+	rom, err := chip8.LoadROMFile(path)
+	if err != nil {
+		log.Printf("could not load ROM: %s", err)
+		return nil, false
+	}
+	return rom, true
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+type runOptions struct {
+	ips            int
+	platform       string
+	quirks         string
+	displayBackend string
+	scale          int
+	braille        bool
+	maxFrames      int
+	mute           bool
+	recordAudio    string
+	sampleRate     int
+	trace          string
+	traceOut       string
+	keymapFile     string
+	stateFile      string
+	rewindMB       int
+	seed           string
+	recordMovie    string
+	playMovie      string
+	breakpoints    string
+
+	//debug starts paused in the debugger, which breakpoints also start
+	debug  bool
+	breaks []uint16
+}
+
+// rewindFrames is how far each press, or key repeat, of the rewind hotkey goes back
+const rewindFrames = chip8.TimerFrequency / 2
+
+func runCommand(args []string) int {
+	return runROM("run", args)
+}
+
+// runROM parses the flags shared by the run and debug commands and starts the ROM
+func runROM(name string, args []string) int {
+	opts := runOptions{debug: name == "debug"}
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.IntVar(&opts.ips, "ips", chip8.DefaultIPS, "instructions executed per second")
+	flags.StringVar(&opts.platform, "platform", "auto", "instruction set: auto, chip8, schip, xochip")
+	flags.StringVar(&opts.quirks, "quirks", "", "interpreter quirks profile: "+strings.Join(chip8.QuirksProfiles(), ", ")+" (default: the platform's)")
+	flags.StringVar(&opts.displayBackend, "display", "", "display backend: sdl, terminal, headless (default: sdl when built with it, otherwise terminal, or headless when stdout is not a terminal or when debugging)")
+	flags.IntVar(&opts.scale, "scale", chip8.DefaultScale, "window pixels per high resolution pixel")
+	flags.BoolVar(&opts.braille, "braille", false, "draw the terminal display with braille instead of half blocks")
+	flags.IntVar(&opts.maxFrames, "frames", 0, "stop after this many frames (0 runs until the program exits)")
+	flags.BoolVar(&opts.mute, "mute", false, "disable sound")
+	flags.StringVar(&opts.recordAudio, "record-audio", "", "render the program's audio into this WAV file instead of playing it")
+	flags.IntVar(&opts.sampleRate, "sample-rate", chip8.DefaultSampleRate, "sample rate of -record-audio in Hz")
+	flags.StringVar(&opts.trace, "trace", "none", "instruction trace format: none, text, json")
+	flags.StringVar(&opts.traceOut, "trace-out", "", "file to write the trace to (default stderr)")
+	flags.StringVar(&opts.keymapFile, "keymap", "", "file of \"<key name> <hex key>\" lines overriding the default keypad layout")
+	flags.IntVar(&opts.rewindMB, "rewind", chip8.DefaultRewindLimit>>20, "megabytes of history kept for the rewind hotkey (0 disables it)")
+	flags.StringVar(&opts.seed, "seed", "", "seed of the random numbers of CXNN, to repeat a run (default: from the time, and reported)")
+	flags.StringVar(&opts.recordMovie, "record-movie", "", "record the session's input and frames into this movie file; disables the state and rewind hotkeys")
+	flags.StringVar(&opts.playMovie, "play-movie", "", "play this movie back headless, checking every frame, instead of running interactively")
+	flags.StringVar(&opts.breakpoints, "break", "", "comma-separated hex addresses to pause at, taking debugger commands from stdin")
+	flags.StringVar(&opts.stateFile, "state", "", "file F5 saves the machine state to and F9 loads it from (default: the ROM file with a .state extension)")
+	path, code, ok := parseFlags(flags, args, "rom")
+	if !ok {
+		return code
+	}
+
+	p := chip8.PlatformCHIP8
+	if opts.platform != "auto" {
+		p, ok = chip8.LookupPlatform(opts.platform)
+		if !ok {
+			log.Printf("unknown platform: %s", opts.platform)
+			return exitUsage
+		}
+	}
+	quirks := chip8.PlatformQuirks(p)
+	if opts.quirks != "" {
+		quirks, ok = chip8.LookupQuirks(opts.quirks)
+		if !ok {
+			log.Printf("unknown quirks profile: %s", opts.quirks)
+			return exitUsage
+		}
+	}
+	if opts.trace != "none" && opts.trace != "text" && opts.trace != "json" {
+		log.Printf("unknown trace format: %s", opts.trace)
+		return exitUsage
+	}
+	seed := uint64(time.Now().UnixNano())
+	if opts.seed != "" {
+		var err error
+		seed, err = strconv.ParseUint(opts.seed, 0, 64)
+		if err != nil {
+			log.Printf("bad seed: %s", opts.seed)
+			return exitUsage
+		}
+	}
+	if opts.sampleRate <= 0 {
+		log.Printf("bad sample rate: %d", opts.sampleRate)
+		return exitUsage
+	}
+	if opts.breaks, ok = parseBreakpoints(opts.breakpoints); !ok {
+		return exitUsage
+	}
+	if opts.displayBackend == "" {
+		opts.displayBackend = defaultDisplay(opts.debug || len(opts.breaks) > 0)
+	}
+	if opts.displayBackend != "sdl" && opts.displayBackend != "terminal" && opts.displayBackend != "headless" {
+		log.Printf("unknown display: %s", opts.displayBackend)
+		return exitUsage
+	}
+	if opts.debug || len(opts.breaks) > 0 {
+		if opts.displayBackend == "terminal" {
+			log.Print("the debugger reads stdin, so it needs -display sdl or headless")
+			return exitUsage
+		}
+		if opts.recordMovie != "" || opts.playMovie != "" {
+			log.Print("movies cannot be recorded or played in the debugger")
+			return exitUsage
+		}
+	}
+
+	rom, ok := loadROM(path)
+	if !ok {
+		return exitROM
+	}
+	if opts.playMovie != "" {
+		return playMovie(opts.playMovie, rom)
+	}
+	if opts.stateFile == "" {
+		opts.stateFile = strings.TrimSuffix(path, filepath.Ext(path)) + ".state"
+	}
+	if opts.platform == "auto" {
+		p = chip8.DetectPlatform(rom)
+		if opts.quirks == "" {
+			quirks = chip8.PlatformQuirks(p)
+		}
+	}
+
+	clock := time.Tick(time.Second / time.Duration(chip8.TimerFrequency))
+	cpu := chip8.NewCPU(clock)
+	cpu.IPS = opts.ips
+	cpu.Random = chip8.NewRandom(seed)
+	if opts.seed == "" {
+		log.Printf("random seed %d", seed)
+	}
+	cpu.Quirks = quirks
+	cpu.SetPlatform(p)
+	if err := cpu.LoadProgram(rom); err != nil {
+		log.Printf("could not load ROM: %s", err)
+		return exitROM
+	}
+
+	return run(cpu, rom, opts)
+}
+
+// run emulates the loaded program until it finishes or the display is closed
+func run(cpu *chip8.CPU, rom []byte, opts runOptions) int {
+	tracer, err := newTracer(opts.trace, opts.traceOut)
+	if err != nil {
+		log.Printf("could not start trace: %s", err)
+		return exitHost
+	}
+	if tracer != nil {
+		cpu.Tracer = tracer
+		if flusher, ok := tracer.(interface{ Flush() error }); ok {
+			defer flusher.Flush()
+		}
+	}
+
+	keymap := chip8.DefaultKeyMap()
+	if opts.keymapFile != "" {
+		keymap, err = chip8.LoadKeyMapFile(opts.keymapFile)
+		if err != nil {
+			log.Printf("could not load keymap: %s", err)
+			return exitUsage
+		}
+	}
+
+	var recorder *chip8.WAVRecorder
+	if opts.recordAudio != "" {
+		file, err := os.Create(opts.recordAudio)
+		if err != nil {
+			log.Printf("could not record audio: %s", err)
+			return exitHost
+		}
+		defer file.Close()
+		recorder, err = chip8.NewWAVRecorder(file, opts.sampleRate)
+		if err != nil {
+			log.Printf("could not record audio: %s", err)
+			return exitUsage
+		}
+		cpu.Sound = recorder
+	} else if !opts.mute {
+		sound, err := chip8.NewSound()
+		switch {
+		case err == nil:
+			cpu.Sound = sound
+			if closer, ok := sound.(io.Closer); ok {
+				defer closer.Close()
+			}
+		case err != chip8.ErrNoSDL:
+			log.Printf("could not open audio: %s", err)
+		}
+	}
+
+	//create display
+	var display chip8.Display
+	switch opts.displayBackend {
+	case "sdl":
+		display, err = chip8.NewDisplay(cpu.Keypad, keymap, opts.scale)
+	case "terminal":
+		mode := chip8.TerminalHalfBlocks
+		if opts.braille {
+			mode = chip8.TerminalBraille
+		}
+		display, err = chip8.NewTerminalDisplay(os.Stdin, os.Stdout, mode, cpu.Keypad, keymap)
+	case "headless":
+		display = chip8.NewHeadlessDisplay(0)
+	}
+	if err != nil {
+		log.Printf("could not open display: %s", err)
+		return exitHost
+	}
+	defer display.Close()
+
+	var movie *chip8.Movie
+	var movieFile *os.File
+	if opts.recordMovie != "" {
+		movieFile, err = os.Create(opts.recordMovie)
+		if err != nil {
+			log.Printf("could not record movie: %s", err)
+			return exitHost
+		}
+		defer movieFile.Close()
+		movie, display, err = chip8.RecordMovie(cpu, rom, display)
+		if err != nil {
+			log.Printf("could not record movie: %s", err)
+			return exitHost
+		}
+	}
+
+	debugging := opts.debug || len(opts.breaks) > 0
+	var rewind *chip8.Rewind
+	if _, ok := display.(chip8.HotkeyDisplay); (ok || debugging) && opts.rewindMB > 0 && movie == nil {
+		rewind = chip8.NewRewind(cpu, chip8.DefaultRewindInterval, opts.rewindMB<<20)
+	}
+	if debugging {
+		startDebugger(cpu, rewind, opts)
+	}
+	cpu.OnHotkey = func(hotkey chip8.Hotkey) {
+		if movie != nil {
+			//a movie only replays if the machine runs straight through
+			return
+		}
+		switch hotkey {
+		case chip8.HotkeySaveState:
+			saveState(cpu, opts.stateFile)
+		case chip8.HotkeyLoadState:
+			if loadState(cpu, opts.stateFile) && rewind != nil {
+				rewind.Reset()
+			}
+		case chip8.HotkeyRewind:
+			if rewind != nil {
+				rewind.StepBackFrames(rewindFrames)
+			}
+		}
+	}
+	if opts.maxFrames > 0 {
+		display = &frameLimit{Display: display, remaining: opts.maxFrames}
+	}
+
+	fault := cpu.Run(display)
+
+	if movie != nil {
+		if err := movie.Write(movieFile); err != nil {
+			log.Printf("could not write movie: %s", err)
+			return exitHost
+		}
+	}
+
+	if recorder != nil {
+		if err := recorder.Finish(cpu.Frames); err != nil {
+			log.Printf("could not write audio: %s", err)
+			return exitHost
+		}
+	}
+	if fault != nil {
+		log.Printf("program stopped: %s", fault)
+		return exitFault
+	}
+	return exitOK
+}
+
+// defaultDisplay picks the display backend when -display is not given: the SDL window if
+// this build has one, otherwise the terminal, which the debugger needs for its commands
+func defaultDisplay(debugging bool) string {
+	if chip8.HaveSDL {
+		return "sdl"
+	}
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 || debugging {
+		return "headless"
+	}
+	return "terminal"
+}
+
+// playMovie plays a movie of rom back, reporting whether it stayed in sync
+func playMovie(path string, rom []byte) int {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("could not open movie: %s", err)
+		return exitUsage
+	}
+	defer file.Close()
+	movie, err := chip8.ReadMovie(file)
+	if err != nil {
+		log.Printf("could not read movie: %s", err)
+		return exitUsage
+	}
+
+	err = movie.Play(chip8.NewCPU(nil), rom)
+	var desync *chip8.DesyncError
+	switch {
+	case errors.As(err, &desync):
+		log.Printf("playback failed: %s", err)
+		return exitDesync
+	case err == chip8.ErrMovieROM:
+		log.Print(err)
+		return exitUsage
+	case err != nil:
+		log.Printf("program stopped: %s", err)
+		return exitFault
+	}
+	log.Printf("played %d frames in sync", len(movie.Frames))
+	return exitOK
+}
+
+// saveState writes the machine state to path, logging the outcome
+func saveState(cpu *chip8.CPU, path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("could not save state: %s", err)
+		return
+	}
+	defer file.Close()
+	if err := cpu.SaveState(file); err != nil {
+		log.Printf("could not save state: %s", err)
+		return
+	}
+	log.Printf("saved state to %s", path)
+}
+
+// loadState restores the machine state from path, logging the outcome
+func loadState(cpu *chip8.CPU, path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("could not load state: %s", err)
+		return false
+	}
+	defer file.Close()
+	if err := cpu.LoadState(file); err != nil {
+		log.Printf("could not load state: %s", err)
+		return false
+	}
+	log.Printf("loaded state from %s", path)
+	return true
+}
+
+// newTracer creates the tracer for a -trace format, or nil when tracing is off
+func newTracer(format, path string) (chip8.Tracer, error) {
+	if format == "none" {
+		return nil, nil
+	}
+	var out io.Writer = os.Stderr
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		//the file is left for the process exit to close
+		out = file
+	}
+	switch format {
+	case "text":
+		return chip8.NewTextTracer(out), nil
+	case "json":
+		return chip8.NewJSONTracer(out), nil
+	}
+	return nil, fmt.Errorf("unknown trace format %q", format)
+}
+
+// frameLimit closes a display after a number of frames
+type frameLimit struct {
+	chip8.Display
+	remaining int
+}
+
+func (f *frameLimit) Draw(fb *chip8.Framebuffer) {
+	f.Display.Draw(fb)
+	f.remaining--
+}
+
+func (f *frameLimit) Closed() bool {
+	return f.remaining <= 0 || f.Display.Closed()
+}
+
+// Hotkeys forwards the hotkeys of the wrapped display, which would otherwise be hidden
+func (f *frameLimit) Hotkeys() []chip8.Hotkey {
+	if hotkeys, ok := f.Display.(chip8.HotkeyDisplay); ok {
+		return hotkeys.Hotkeys()
+	}
+	return nil
+}
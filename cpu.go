@@ -1,7 +1,6 @@
 package chip8
 
 import (
-	"crypto/rand"
 	"fmt"
 	"time"
 )
@@ -9,6 +8,9 @@ import (
 // Wordlength is the number of bytes for a processor word
 const WordLength = 2
 
+// DefaultPitch is the XO-CHIP pitch register at reset, playing the audio pattern at 4000 bits per second
+const DefaultPitch = 64
+
 // CPU is a virtual machine
 type CPU struct {
 
@@ -33,394 +35,547 @@ type CPU struct {
 	//sound timer
 	ST byte
 
-	//Memory
-	Memory [4096]byte
+	//Memory, sized for the platform by SetPlatform
+	Memory []byte
+
+	//video memory
+	Framebuffer *Framebuffer
+
+	//instruction set in use
+	Platform Platform
+
+	//interpreter behaviour for ambiguous opcodes
+	Quirks Quirks
+
+	//SUPER-CHIP RPL user flags (FX75/FX85)
+	RPL [16]byte
+
+	//XO-CHIP audio pattern buffer (F002) and pitch register (FX3A)
+	AudioPattern [16]byte
+	Pitch        byte
+
+	//DXYN asked to end the frame early (Quirks.DisplayWait)
+	waitVBlank bool
+
+	//receives every executed instruction, when set
+	Tracer Tracer
+
+	//hex keypad
+	Keypad Keypad
 
+	//numbers for CXNN, seeded from the time by NewCPU
+	Random RandomSource
+
+	//records history for stepping backwards, when set by NewRewind
+	Rewind *Rewind
+
+	//stops Run at breakpoints and takes commands while paused, when set by NewDebugger
+	Debugger *Debugger
+
+	//called by Run for each hotkey pressed on a HotkeyDisplay, when set
+	OnHotkey func(Hotkey)
+
+	//beeper driven by the sound timer, silent when nil
+	Sound   Sound
+	beeping bool
+
+	//FX0A state: a key has been pressed and we are waiting for its release
+	keyWaiting bool
+	keyWait    byte
+
+	//ticks at TimerFrequency; each tick runs one frame
 	Clock <-chan time.Time
 
+	//instructions executed per second
+	IPS int
+
+	//60Hz frames and instructions executed so far
+	Frames uint64
+	Cycles uint64
+
+	//instructions executed in the current frame
+	frameCycle int
+
 	Finished bool
 }
 
 func NewCPU(timer <-chan time.Time) *CPU {
-	return &CPU{
-		Clock: timer,
-		PC:    0x200,
+	c := &CPU{
+		Clock:       timer,
+		PC:          ProgramAddress,
+		Memory:      make([]byte, PlatformCHIP8.MemorySize()),
+		Pitch:       DefaultPitch,
+		Framebuffer: NewFramebuffer(ScreenWidth, ScreenHeight),
+		Keypad:      NewKeypad(),
+		IPS:         DefaultIPS,
+		Quirks:      VIP,
+		Random:      NewRandom(uint64(time.Now().UnixNano())),
 	}
+	c.LoadData(FontAddress, DefaultFont)
+	c.LoadData(LargeFontAddress, LargeFont)
+	return c
 }
 
-func (c *CPU) Run(display Display) {
-	fmt.Println("Running Chip8")
-	fmt.Println("Starting...")
-	if c.Clock == nil {
-		fmt.Println("No Clock")
-		return
+// SetPlatform selects the instruction set, resizing memory to suit it
+func (c *CPU) SetPlatform(p Platform) {
+	c.Platform = p
+	if size := p.MemorySize(); size != len(c.Memory) {
+		memory := make([]byte, size)
+		copy(memory, c.Memory)
+		c.Memory = memory
 	}
-	for {
-		select {
-		case <-c.Clock:
-			c.Execute()
-			if display != nil {
-				display.Draw(c.Memory[0xF00:], PIXELS_MONOCHROME) //todo get vram
-			}
-			if c.Finished {
-				fmt.Println("Finished")
-				return
-			}
-		}
+}
+
+// skipNext advances PC past the next instruction, which is 4 bytes long for XO-CHIP's F000 NNNN
+func (c *CPU) skipNext() {
+	c.PC += WordLength
+	c.PC += uint16(DecodeAt(c.Memory, int(c.PC), c.Platform).Length)
+}
+
+// advanceI moves I past the registers FX55 or FX65 transferred, as the quirks say
+func (c *CPU) advanceI(x byte) {
+	switch {
+	case c.Quirks.IncrementI:
+		c.I += uint16(x) + 1
+	case c.Quirks.IncrementIByX:
+		c.I += uint16(x)
 	}
 }
 
-func (c *CPU) ExecuteOp(opCode uint16) {
-	switch opCode & 0xF000 {
-	case 0x0000:
-		switch opCode {
-		case 0x00E0:
-			empty := [256]byte{}
-			c.LoadData(0xF00, empty[:])
-			//clear screen
-			c.PC += WordLength
-		case 0x00EE:
-			//return from subroutrine
-			c.PC = c.PopFromStack()
-			c.PC += WordLength
+func (c *CPU) opCodeAt(addr uint16) uint16 {
+	if int(addr)+1 >= len(c.Memory) {
+		return 0
+	}
+	return uint16(c.Memory[addr])<<8 | uint16(c.Memory[addr+1])
+}
 
-		default:
-			// ignore RCA1802 functions
-			//NOP
-			c.PC += WordLength
+// ExecuteOp executes a single instruction. When it fails the CPU is left as it was before
+// the instruction, with PC still pointing at it.
+func (c *CPU) ExecuteOp(opCode uint16) error {
+	inst := Decode(opCode, c.Platform)
+	x, y := inst.X, inst.Y
+	switch inst.Op {
+	case OpSys:
+		// ignore RCA1802 functions
+		//NOP
+		c.PC += WordLength
+	case OpClear:
+		//clear screen (0x00E0)
+		c.Framebuffer.Clear()
+		c.PC += WordLength
+	case OpReturn:
+		//return from subroutrine (0x00EE)
+		addr, err := c.PopFromStack()
+		if err != nil {
+			return err
 		}
-	case 0x1000:
+		c.PC = addr + WordLength
+	case OpScrollDown:
+		//scroll down N rows (0x00CN, SUPER-CHIP)
+		c.Framebuffer.ScrollDown(int(inst.N))
+		c.PC += WordLength
+	case OpScrollUp:
+		//scroll up N rows (0x00DN, XO-CHIP)
+		c.Framebuffer.ScrollUp(int(inst.N))
+		c.PC += WordLength
+	case OpScrollRight:
+		//scroll right 4 pixels (0x00FB, SUPER-CHIP)
+		c.Framebuffer.ScrollRight(4)
+		c.PC += WordLength
+	case OpScrollLeft:
+		//scroll left 4 pixels (0x00FC, SUPER-CHIP)
+		c.Framebuffer.ScrollLeft(4)
+		c.PC += WordLength
+	case OpExit:
+		//exit the interpreter (0x00FD, SUPER-CHIP)
+		c.Finished = true
+	case OpLowRes:
+		//switch to 64x32 low resolution (0x00FE, SUPER-CHIP)
+		c.Framebuffer.SetHiRes(false)
+		c.PC += WordLength
+	case OpHighRes:
+		//switch to 128x64 high resolution (0x00FF, SUPER-CHIP)
+		c.Framebuffer.SetHiRes(true)
+		c.PC += WordLength
+	case OpJump:
 		//jump to address NNN (0x1NNN)
-		addr := opCode & 0x0FFF
-		c.PC = addr
-	case 0x2000:
+		c.PC = inst.NNN
+	case OpCall:
 		//call subroutine NNN (0x2NNN)
-		c.PushToStack(c.PC)
-		c.PC = opCode & 0x0FFF
-	case 0x3000:
+		if err := c.PushToStack(c.PC); err != nil {
+			return err
+		}
+		c.PC = inst.NNN
+	case OpSkipEqualImm:
 		// skip next instruction if V[X]==NN (0x3XNN)
-		x := (opCode & 0x0F00) >> 8
-		vx := c.V[x]
-		imm := byte(opCode & 0x00FF)
-		if imm == vx {
-			c.PC += 2 * WordLength
+		if c.V[x] == inst.NN {
+			c.skipNext()
 		} else {
 			c.PC += WordLength
 		}
-	case 0x4000:
+	case OpSkipNotEqualImm:
 		// skip next instruction if V[X]!=NN (0x4XNN)
-		x := (opCode & 0x0F00) >> 8
-		vx := c.V[x]
-		imm := byte(opCode & 0x00FF)
-		if imm != vx {
-			c.PC += 2 * WordLength
+		if c.V[x] != inst.NN {
+			c.skipNext()
 		} else {
 			c.PC += WordLength
 		}
-	case 0x5000:
+	case OpSkipEqual:
 		// skip next instruction if V[X]==V[Y] (0x5XY0)
-		x := (opCode & 0x0F00) >> 8
-		vx := c.V[x]
-		y := (opCode & 0x00F0) >> 4
-		vy := c.V[y]
-		if vy == vx {
-			c.PC += 2 * WordLength
+		if c.V[x] == c.V[y] {
+			c.skipNext()
 		} else {
 			c.PC += WordLength
 		}
-	case 0x6000:
+	case OpSaveRange:
+		//store V[X] to V[Y] (inclusive, in either order) at memory location I (0x5XY2, XO-CHIP)
+		regs := registerRange(opCode)
+		if err := c.checkMemory(int(c.I), len(regs)); err != nil {
+			return err
+		}
+		for i, r := range regs {
+			c.Memory[int(c.I)+i] = c.V[r]
+		}
+		c.PC += WordLength
+	case OpLoadRange:
+		//set V[X] to V[Y] (inclusive, in either order) to values from location I (0x5XY3, XO-CHIP)
+		regs := registerRange(opCode)
+		if err := c.checkMemory(int(c.I), len(regs)); err != nil {
+			return err
+		}
+		for i, r := range regs {
+			c.V[r] = c.Memory[int(c.I)+i]
+		}
+		c.PC += WordLength
+	case OpSetImm:
 		//set V[X] to NN (0x6XNN)
-		x := (opCode & 0x0F00) >> 8
-		imm := byte(opCode & 0x00FF)
-		c.V[x] = imm
+		c.V[x] = inst.NN
 		c.PC += WordLength
-	case 0x7000:
+	case OpAddImm:
 		//set V[X] to V[X}+NN (0x7XNN)
 		//don't change carry bit
-		x := (opCode & 0x0F00) >> 8
-		imm := byte(opCode & 0x00FF)
-		c.V[x] = imm + c.V[x]
-		c.PC += WordLength
-	case 0x8000:
-		switch opCode & 0x000F {
-		case 0x0000:
-			//set V[X] to V[Y] (0x8XY0)
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			vy := c.V[y]
-			c.V[x] = vy
-			c.PC += WordLength
-		case 0x0001:
-			//set V[X] to V[X] OR V[Y] (0x8XY1)
-			x := (opCode & 0x0F00) >> 8
-			vx := c.V[x]
-			y := (opCode & 0x00F0) >> 4
-			vy := c.V[y]
-			c.V[x] = vx | vy
-			c.PC += WordLength
-		case 0x0002:
-			//set V[X] to V[X] AND V[Y] (0x8XY2)
-			x := (opCode & 0x0F00) >> 8
-			vx := c.V[x]
-			y := (opCode & 0x00F0) >> 4
-			vy := c.V[y]
-			c.V[x] = vx & vy
-			c.PC += WordLength
-		case 0x0003:
-			//set V[X] to V[X] XOR V[Y] (0x8XY3)
-			x := (opCode & 0x0F00) >> 8
-			vx := c.V[x]
-			y := (opCode & 0x00F0) >> 4
-			vy := c.V[y]
-			c.V[x] = vx ^ vy
-			c.PC += WordLength
-		case 0x0004:
-			//set V[X] to V[X] + V[Y] (0x8XY4), set V[F] to 1 if carry, otherwise 0
-			x := (opCode & 0x0F00) >> 8
-			vx := c.V[x]
-			y := (opCode & 0x00F0) >> 4
-			vy := c.V[y]
-			c.V[x] = vx + vy
-			if int(vx)+int(vy) > 0xFF {
-				c.V[0xF] |= 0x01
-			} else {
-				c.V[0xF] &= 0xFE
-			}
-			c.PC += WordLength
-		case 0x0005:
-			//set V[X] to V[X] - V[Y] (0x8XY5), set V[F] to 1 if no borrow, otherwise 0
-			x := (opCode & 0x0F00) >> 8
-			vx := c.V[x]
-			y := (opCode & 0x00F0) >> 4
-			vy := c.V[y]
-			if vx > vy {
-				c.V[x] = vx - vy
-				c.V[0xF] |= 0x01
-			} else {
-				c.V[0xF] &= 0xFE
-			}
-			c.PC += WordLength
-		case 0x0006:
-			//set V[X] to V[Y] >> 1 (0x8XY6), set V[F] to V[Y] LSB before shift
-			x := (opCode & 0x0F00) >> 8
-
-			y := (opCode & 0x00F0) >> 4
-			vy := c.V[y]
-			c.V[0xF] = vy & 0x01
-			c.V[x] = vy >> 1
-
-			c.PC += WordLength
-		case 0x0007:
-			//set V[X] to V[Y] - V[X] (0x8XY7), set V[F] to 1 if no borrow, otherwise 0
-			x := (opCode & 0x0F00) >> 8
-			vx := c.V[x]
-			y := (opCode & 0x00F0) >> 4
-			vy := c.V[y]
-			if vx < vy {
-				c.V[x] = vy - vx
-				c.V[0xF] |= 0x01
-			} else {
-				c.V[0xF] &= 0xFE
-			}
-			c.PC += WordLength
-		case 0x000E:
-			//set V[X] (and V[Y]) to V[Y] << 1 (0x8XYE), set V[F] to V[Y] MSB before shift
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			vy := c.V[y]
-			c.V[0xF] = c.V[0xF] & (((vy & 0x80) >> 7) | 0xFE)
-			c.V[x] = vy << 1
-			c.V[y] = vy << 1
-
-			c.PC += WordLength
+		c.V[x] += inst.NN
+		c.PC += WordLength
+	case OpSet:
+		//set V[X] to V[Y] (0x8XY0)
+		c.V[x] = c.V[y]
+		c.PC += WordLength
+	case OpOr:
+		//set V[X] to V[X] OR V[Y] (0x8XY1)
+		c.V[x] |= c.V[y]
+		if c.Quirks.ResetVF {
+			c.V[0xF] = 0
+		}
+		c.PC += WordLength
+	case OpAnd:
+		//set V[X] to V[X] AND V[Y] (0x8XY2)
+		c.V[x] &= c.V[y]
+		if c.Quirks.ResetVF {
+			c.V[0xF] = 0
+		}
+		c.PC += WordLength
+	case OpXor:
+		//set V[X] to V[X] XOR V[Y] (0x8XY3)
+		c.V[x] ^= c.V[y]
+		if c.Quirks.ResetVF {
+			c.V[0xF] = 0
+		}
+		c.PC += WordLength
+	case OpAdd:
+		//set V[X] to V[X] + V[Y] (0x8XY4), set V[F] to 1 if carry, otherwise 0
+		vx, vy := c.V[x], c.V[y]
+		c.V[x] = vx + vy
+		if int(vx)+int(vy) > 0xFF {
+			c.V[0xF] |= 0x01
+		} else {
+			c.V[0xF] &= 0xFE
+		}
+		c.PC += WordLength
+	case OpSub:
+		//set V[X] to V[X] - V[Y] (0x8XY5), set V[F] to 1 if no borrow, otherwise 0
+		vx, vy := c.V[x], c.V[y]
+		if vx > vy {
+			c.V[x] = vx - vy
+			c.V[0xF] |= 0x01
+		} else {
+			c.V[0xF] &= 0xFE
+		}
+		c.PC += WordLength
+	case OpShiftRight:
+		//set V[X] to V[Y] >> 1 (0x8XY6), set V[F] to V[Y] LSB before shift
+		//with Quirks.ShiftVX, V[X] is shifted in place instead
+		src := c.V[y]
+		if c.Quirks.ShiftVX {
+			src = c.V[x]
+		}
+		c.V[x] = src >> 1
+		c.V[0xF] = src & 0x01
+		c.PC += WordLength
+	case OpSubReverse:
+		//set V[X] to V[Y] - V[X] (0x8XY7), set V[F] to 1 if no borrow, otherwise 0
+		vx, vy := c.V[x], c.V[y]
+		if vx < vy {
+			c.V[x] = vy - vx
+			c.V[0xF] |= 0x01
+		} else {
+			c.V[0xF] &= 0xFE
+		}
+		c.PC += WordLength
+	case OpShiftLeft:
+		//set V[X] to V[Y] << 1 (0x8XYE), set V[F] to V[Y] MSB before shift
+		//with Quirks.ShiftVX, V[X] is shifted in place instead
+		src := c.V[y]
+		if c.Quirks.ShiftVX {
+			src = c.V[x]
 		}
-	case 0x9000:
+		c.V[x] = src << 1
+		c.V[0xF] = src >> 7
+		c.PC += WordLength
+	case OpSkipNotEqual:
 		//skip next instruction if V[X]!=V[Y] (0x9XY0)
-		x := (opCode & 0x0F00) >> 8
-		vx := c.V[x]
-		y := (opCode & 0x00F0) >> 4
-		vy := c.V[y]
-		if vy != vx {
-			c.PC += 2 * WordLength
+		if c.V[x] != c.V[y] {
+			c.skipNext()
 		} else {
 			c.PC += WordLength
 		}
-	case 0xA000:
+	case OpSetI:
 		//set I to NNN (0xANNN)
-		addr := opCode & 0x0FFF
-		c.I = addr
+		c.I = inst.NNN
 		c.PC += WordLength
-	case 0xB000:
+	case OpJumpOffset:
 		//jump to addr V[0]+NNN: set PC to V[0] +NNN (0xBNNN)
-		addr := opCode & 0x0FFF
-		c.PushToStack(c.PC)
-		c.PC = addr + uint16(c.V[0])
-	case 0xC000:
+		//with Quirks.JumpVX, set PC to V[X] + XNN (0xBXNN)
+		offset := c.V[0]
+		if c.Quirks.JumpVX {
+			offset = c.V[x]
+		}
+		c.PC = inst.NNN + uint16(offset)
+	case OpRandom:
 		//set V[x] to R & NN where R = random number between 0 and 255(0xCXNN)
-		rnd := []byte{0xFF}
-		rand.Read(rnd)
-		x := (opCode & 0x0F00) >> 8
-		imm := byte(opCode & 0x00FF)
-		c.V[x] = imm & rnd[0]
+		c.V[x] = inst.NN & c.Random.Byte()
 		c.PC += WordLength
-	case 0xD000:
+	case OpDraw:
 		//DXYN
 		//draw sprite at position (V[X],V[Y]) with width 8, heigh N.
 		//sprite bits located at Memory[I] in rows of 8 (0xDXYN)
 		//V[F] is set to 1 if pixels are flipped from 1 to 0, otherwise 0
-		//TODO
-		height := opCode & 0x000F
-		x := (opCode & 0x0F00) >> 8
-		y := (opCode & 0x00F0) >> 4
-
-		sprite := c.Memory[c.I:height]
-
-		vramLocation := c.Memory[0xf00+((y*32)+x) : len(sprite)]
+		//on SUPER-CHIP, DXY0 draws a 16x16 sprite from 32 bytes at Memory[I]
 
-		collide := false
-
-		for i := 0; i < len(sprite); i++ {
-			if sprite[i]^vramLocation[i] > 0 {
-				collide = true
-				break
-			}
+		//on XO-CHIP, each selected plane reads its own sprite data from consecutive memory
+		large := inst.N == 0 && c.Platform >= PlatformSCHIP
+		size := int(inst.N)
+		if large {
+			size = 32
 		}
+		size *= c.Framebuffer.SelectedPlaneCount()
 
-		c.LoadData(0xf00+((y*32)+x), sprite)
+		if err := c.checkMemory(int(c.I), size); err != nil {
+			return err
+		}
+		sprite := c.Memory[int(c.I) : int(c.I)+size]
 
-		if collide {
-			c.V[0xF] |= 0x01
+		var collision bool
+		if large {
+			collision = c.Framebuffer.DrawLargeSprite(int(c.V[x]), int(c.V[y]), sprite, c.Quirks.WrapSprites)
+		} else {
+			collision = c.Framebuffer.DrawSprite(int(c.V[x]), int(c.V[y]), sprite, c.Quirks.WrapSprites)
 		}
+		if collision {
+			c.V[0xF] = 0x01
+		} else {
+			c.V[0xF] = 0x00
+		}
+		c.waitVBlank = c.Quirks.DisplayWait
 
 		c.PC += WordLength
-	case 0xE000:
-		switch opCode & 0x00FF {
-		case 0x009E:
-			//skip next instruction if key pressed == v[X] (0xEX9E)
-			//TODO
-			c.PC += WordLength
-		case 0x00A1:
-			//skip next instruction if key pressed != v[X] (0xEXA1)
-			//TODO
+	case OpSkipKey:
+		//skip next instruction if key pressed == v[X] (0xEX9E)
+		if c.Keypad.Pressed(c.V[x]) {
+			c.skipNext()
+		} else {
 			c.PC += WordLength
 		}
-	case 0xF000:
-		switch opCode & 0x00FF {
-		case 0x0007:
-			//set V[X] to DT (0xFX07)
-			x := (opCode & 0x0F00) >> 8
-			c.V[x] = c.DT
-			c.PC += WordLength
-
-		case 0x000A:
-			//set v[X] to key pressed (0xFX0A)
-			//TODO
-			c.PC += WordLength
-		case 0x0015:
-			//set DT to V[X] (0xFX15)
-			x := (opCode & 0x0F00) >> 8
-			c.DT = c.V[x]
-			c.PC += WordLength
-		case 0x0018:
-			//set ST to V[X] (0xFX18)
-			x := (opCode & 0x0F00) >> 8
-			c.ST = c.V[x]
-			c.PC += WordLength
-		case 0x001E:
-			//set I = I + V[X] (0xFX1E)
-			x := (opCode & 0x0F00) >> 8
-			c.I += uint16(c.V[x])
-			c.PC += WordLength
-		case 0x0029:
-			//set I to sprite address for glyph of V[X] (4x5 px font) (0xFX29)
-			x := (opCode & 0x0F00) >> 8
-			c.I = uint16(c.V[x] * 5) //each glyph is 5 bytes
-			c.PC += WordLength
-		case 0x0033:
-			//get BCD representation of V[X]
-			//Memory[I+0] = Decimal MSB Digit (3)
-			//Memory[I+1] = Decimal Middle Digit (2)
-			//Memory[I+3] = Decimal LSB Digit (3)
-			//(0xFX33)
-			x := (opCode & 0x0F00) >> 8
-			c.Memory[c.I] = c.V[x] / 100
-			c.Memory[c.I+1] = (c.V[x] / 10) % 10
-			c.Memory[c.I+2] = (c.V[x] % 100) % 10
-			c.PC += WordLength
-		case 0x0055:
-			//Store V[0] to V[X] (inclusive) at memory location I, increasing I per register
-			//(0xFX55)
-			for i := 0; i < 15; i++ {
-				c.I += uint16(i)
-				c.Memory[c.I] = c.V[i]
-			}
+	case OpSkipNotKey:
+		//skip next instruction if key pressed != v[X] (0xEXA1)
+		if !c.Keypad.Pressed(c.V[x]) {
+			c.skipNext()
+		} else {
 			c.PC += WordLength
-		case 0x0065:
-			//Set V[0] to V[x] (inclusive) to values from location I, increasing I per register
-			//(0xFX65)
-			for i := 0; i < 15; i++ {
-				c.I += uint16(i)
-				c.V[i] = c.Memory[c.I]
+		}
+	case OpSetILong:
+		//set I to the 16 bit address NNNN in the following word (0xF000 0xNNNN, XO-CHIP)
+		if err := c.checkMemory(int(c.PC)+WordLength, WordLength); err != nil {
+			return err
+		}
+		c.I = c.opCodeAt(c.PC + WordLength)
+		c.PC += 2 * WordLength
+	case OpPlanes:
+		//select drawing planes N (0xFN01, XO-CHIP)
+		c.Framebuffer.SelectPlanes(x)
+		c.PC += WordLength
+	case OpAudio:
+		//load the 16 byte audio pattern buffer from memory location I (0xF002, XO-CHIP)
+		if err := c.checkMemory(int(c.I), len(c.AudioPattern)); err != nil {
+			return err
+		}
+		copy(c.AudioPattern[:], c.Memory[c.I:])
+		c.updatePattern()
+		c.PC += WordLength
+	case OpGetDelay:
+		//set V[X] to DT (0xFX07)
+		c.V[x] = c.DT
+		c.PC += WordLength
+	case OpWaitKey:
+		//set v[X] to key pressed (0xFX0A)
+		//like the COSMAC VIP, block until a key is pressed and then released.
+		//PC is not advanced while waiting so the instruction repeats.
+		if !c.keyWaiting {
+			for key := byte(0); key < KeyCount; key++ {
+				if c.Keypad.Pressed(key) {
+					c.keyWaiting = true
+					c.keyWait = key
+					break
+				}
 			}
+		} else if !c.Keypad.Pressed(c.keyWait) {
+			c.keyWaiting = false
+			c.V[x] = c.keyWait
 			c.PC += WordLength
-		default:
-			//nop
-			c.PC += WordLength
 		}
+	case OpSetDelay:
+		//set DT to V[X] (0xFX15)
+		c.DT = c.V[x]
+		c.PC += WordLength
+	case OpSetSound:
+		//set ST to V[X] (0xFX18)
+		c.ST = c.V[x]
+		c.updateSound()
+		c.PC += WordLength
+	case OpAddI:
+		//set I = I + V[X] (0xFX1E)
+		c.I += uint16(c.V[x])
+		c.PC += WordLength
+	case OpFont:
+		//set I to sprite address for glyph of V[X] (4x5 px font) (0xFX29)
+		c.I = FontAddress + uint16(c.V[x]&0x0F)*5 //each glyph is 5 bytes
+		c.PC += WordLength
+	case OpLargeFont:
+		//set I to sprite address for glyph of V[X] (8x10 px font) (0xFX30, SUPER-CHIP)
+		c.I = LargeFontAddress + uint16(c.V[x]&0x0F)*10 //each glyph is 10 bytes
+		c.PC += WordLength
+	case OpBCD:
+		//get BCD representation of V[X]
+		//Memory[I+0] = Decimal MSB Digit (3)
+		//Memory[I+1] = Decimal Middle Digit (2)
+		//Memory[I+3] = Decimal LSB Digit (3)
+		//(0xFX33)
+		if err := c.checkMemory(int(c.I), 3); err != nil {
+			return err
+		}
+		c.Memory[c.I] = c.V[x] / 100
+		c.Memory[c.I+1] = (c.V[x] / 10) % 10
+		c.Memory[c.I+2] = (c.V[x] % 100) % 10
+		c.PC += WordLength
+	case OpPitch:
+		//set the audio pitch register to V[X] (0xFX3A, XO-CHIP)
+		c.Pitch = c.V[x]
+		c.updatePattern()
+		c.PC += WordLength
+	case OpStore:
+		//Store V[0] to V[X] (inclusive) at memory location I
+		//with Quirks.IncrementI, I is left at I+X+1, with Quirks.IncrementIByX at I+X
+		//(0xFX55)
+		if err := c.checkMemory(int(c.I), int(x)+1); err != nil {
+			return err
+		}
+		copy(c.Memory[c.I:], c.V[:x+1])
+		c.advanceI(x)
+		c.PC += WordLength
+	case OpLoad:
+		//Set V[0] to V[x] (inclusive) to values from location I
+		//with Quirks.IncrementI, I is left at I+X+1, with Quirks.IncrementIByX at I+X
+		//(0xFX65)
+		if err := c.checkMemory(int(c.I), int(x)+1); err != nil {
+			return err
+		}
+		copy(c.V[:x+1], c.Memory[c.I:])
+		c.advanceI(x)
+		c.PC += WordLength
+	case OpSaveFlags:
+		//Store V[0] to V[X] (inclusive) in the RPL user flags (0xFX75, SUPER-CHIP)
+		copy(c.RPL[:x+1], c.V[:x+1])
+		c.PC += WordLength
+	case OpLoadFlags:
+		//Set V[0] to V[X] (inclusive) from the RPL user flags (0xFX85, SUPER-CHIP)
+		copy(c.V[:x+1], c.RPL[:x+1])
+		c.PC += WordLength
+	default:
+		return ErrInvalidOpcode{Opcode: opCode, PC: c.PC}
 	}
+	return nil
 }
 
-func (c *CPU) Execute() {
-
-	if c.PC > 4083 {
-		c.Finished = true
-		return
+// Execute fetches and executes the instruction at PC
+func (c *CPU) Execute() error {
+	if err := c.checkMemory(int(c.PC), WordLength); err != nil {
+		return err
 	}
 
-	if c.DT > 0 {
-		c.DT -= 1
-	}
-	if c.ST > 0 {
-		c.ST -= 1
+	pc := c.PC
+	opCode := c.opCodeAt(pc)
+	if c.Tracer == nil {
+		return c.ExecuteOp(opCode)
 	}
 
-	opCode := uint16(c.Memory[c.PC])<<8 | uint16(c.Memory[c.PC+1])
-	c.ExecuteOp(opCode)
-
-	fmt.Printf("\n[%s] PC: %#x SP: %#x\n", disassemble(opCode), c.PC, c.SP)
-	for i, data := range c.V {
-		fmt.Printf(" V%d: %#x", i, data)
+	before := c.registers()
+	if err := c.ExecuteOp(opCode); err != nil {
+		return err
 	}
-
+	c.trace(pc, opCode, before)
+	return nil
 }
 
-func (c *CPU) PushToStack(addr uint16) {
-
-	if c.SP < byte(len(c.Stack)) {
-		c.SP++
-		c.Stack[c.SP] = addr
-	} else {
-		panic("Stack overflow")
+// PushToStack saves a return address. SP counts the addresses on the stack.
+func (c *CPU) PushToStack(addr uint16) error {
+	if int(c.SP) >= len(c.Stack) {
+		return ErrStackOverflow
 	}
-
+	c.Stack[c.SP] = addr
+	c.SP++
+	return nil
 }
 
-func (c *CPU) PopFromStack() uint16 {
-	addr := c.Stack[c.SP]
-	if c.SP > 0 {
-		c.SP--
-	} else {
-		panic("stack overflow")
+func (c *CPU) PopFromStack() (uint16, error) {
+	if c.SP == 0 {
+		return 0, ErrStackUnderflow
 	}
-	return addr
+	c.SP--
+	return c.Stack[c.SP], nil
 }
 
-func (c *CPU) LoadData(addr uint16, data []byte) {
+// LoadData copies data into memory at addr. Nothing is copied if it does not fit.
+func (c *CPU) LoadData(addr uint16, data []byte) error {
+	if int(addr)+len(data) > len(c.Memory) {
+		return fmt.Errorf("%w: %d bytes at %#04x", ErrMemoryOutOfBounds, len(data), addr)
+	}
+	copy(c.Memory[addr:], data)
+	return nil
+}
 
-	for i, b := range data {
-		if (int(addr) + i) > len(c.Memory) {
-			return
+// registerRange returns the registers from X to Y of 0x5XYN, counting down when X > Y
+func registerRange(opCode uint16) []uint16 {
+	x := (opCode & 0x0F00) >> 8
+	y := (opCode & 0x00F0) >> 4
+	var regs []uint16
+	for r := x; ; {
+		regs = append(regs, r)
+		if r == y {
+			return regs
+		}
+		if x < y {
+			r++
+		} else {
+			r--
 		}
-		c.Memory[int(addr)+i] = b
 	}
 }
@@ -11,8 +11,21 @@ func TestOpCode0NNN(t *testing.T) {
 	t.Skip()
 }
 func TestOpCode00E0(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.Framebuffer.SetPixel(3, 4, true)
+	cpu.Framebuffer.SetPixel(63, 31, true)
+	cpu.ExecuteOp(0x00E0)
+	for y := 0; y < chip8.ScreenHeight; y++ {
+		for x := 0; x < chip8.ScreenWidth; x++ {
+			if cpu.Framebuffer.Pixel(x, y) {
+				t.Errorf("pixel (%d,%d) not cleared", x, y)
+			}
+		}
+	}
+	if cpu.PC != 0x202 {
+		t.Log("pc value not expected")
+		t.Fail()
+	}
 }
 func TestOpCode00EE(t *testing.T) {
 	//cpu := chip8.NewCPU(nil)
@@ -135,6 +148,24 @@ func TestOpCode8XY1(t *testing.T) {
 	}
 
 }
+func TestOpCode8XY1ResetVF(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.Quirks = chip8.VIP
+	cpu.V[0xF] = 0x01
+	cpu.ExecuteOp(0x8011)
+	if cpu.V[0xF] != 0 {
+		t.Log("VIP: V[F] not reset")
+		t.Fail()
+	}
+
+	cpu.Quirks = chip8.CHIP48
+	cpu.V[0xF] = 0x01
+	cpu.ExecuteOp(0x8011)
+	if cpu.V[0xF] != 1 {
+		t.Log("CHIP48: V[F] reset")
+		t.Fail()
+	}
+}
 func TestOpCode8XY2(t *testing.T) {
 	cpu := chip8.NewCPU(nil)
 	op := uint16(0x8012)
@@ -195,8 +226,22 @@ func TestOpCode8XY5(t *testing.T) {
 	}
 }
 func TestOpCode8XY6(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.Quirks = chip8.VIP
+	cpu.V[0] = 0x10
+	cpu.V[1] = 0x05
+	cpu.ExecuteOp(0x8016)
+	if cpu.V[0] != 0x02 || cpu.V[0xF] != 0x01 {
+		t.Errorf("VIP: V[0] %#x, V[F] %#x", cpu.V[0], cpu.V[0xF])
+	}
+
+	cpu.Quirks = chip8.CHIP48
+	cpu.V[0] = 0x10
+	cpu.V[1] = 0x05
+	cpu.ExecuteOp(0x8016)
+	if cpu.V[0] != 0x08 || cpu.V[0xF] != 0x00 {
+		t.Errorf("CHIP48: V[0] %#x, V[F] %#x", cpu.V[0], cpu.V[0xF])
+	}
 }
 func TestOpCode8XY7(t *testing.T) {
 	cpu := chip8.NewCPU(nil)
@@ -217,8 +262,26 @@ func TestOpCode8XY7(t *testing.T) {
 	}
 }
 func TestOpCode8XYE(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.Quirks = chip8.VIP
+	cpu.V[0] = 0x01
+	cpu.V[1] = 0x81
+	cpu.ExecuteOp(0x801E)
+	if cpu.V[0] != 0x02 || cpu.V[0xF] != 0x01 {
+		t.Errorf("VIP: V[0] %#x, V[F] %#x", cpu.V[0], cpu.V[0xF])
+	}
+	if cpu.V[1] != 0x81 {
+		t.Log("V[Y] modified")
+		t.Fail()
+	}
+
+	cpu.Quirks = chip8.CHIP48
+	cpu.V[0] = 0x01
+	cpu.V[1] = 0x81
+	cpu.ExecuteOp(0x801E)
+	if cpu.V[0] != 0x02 || cpu.V[0xF] != 0x00 {
+		t.Errorf("CHIP48: V[0] %#x, V[F] %#x", cpu.V[0], cpu.V[0xF])
+	}
 }
 func TestOpCode9XY0(t *testing.T) {
 	//cpu := chip8.NewCPU(nil)
@@ -229,32 +292,131 @@ func TestOpCodeANNN(t *testing.T) {
 	t.Skip()
 }
 func TestOpCodeDXYN(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.LoadData(0x300, []byte{0xF0, 0x90})
+	cpu.I = 0x300
+	cpu.V[2] = 10
+	cpu.V[3] = 5
+
+	cpu.ExecuteOp(0xD232)
+	for x := 10; x < 14; x++ {
+		if !cpu.Framebuffer.Pixel(x, 5) {
+			t.Errorf("pixel (%d,5) not drawn", x)
+		}
+	}
+	if !cpu.Framebuffer.Pixel(10, 6) || cpu.Framebuffer.Pixel(11, 6) || !cpu.Framebuffer.Pixel(13, 6) {
+		t.Log("second sprite row not expected")
+		t.Fail()
+	}
+	if cpu.Framebuffer.Pixel(2, 3) {
+		t.Log("sprite drawn at register indices instead of register values")
+		t.Fail()
+	}
+	if cpu.V[0xF] != 0 {
+		t.Log("v[F] set without collision")
+		t.Fail()
+	}
+	if cpu.PC != 0x202 {
+		t.Log("pc value not expected")
+		t.Fail()
+	}
+
+	//drawing the same sprite again erases it and collides
+	cpu.ExecuteOp(0xD232)
+	if cpu.Framebuffer.Pixel(10, 5) || cpu.Framebuffer.Pixel(13, 6) {
+		t.Log("sprite not XORed off")
+		t.Fail()
+	}
+	if cpu.V[0xF] != 1 {
+		t.Log("v[F] not set on collision")
+		t.Fail()
+	}
 }
 func TestOpCodeBNNN(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.Quirks = chip8.VIP
+	cpu.V[0] = 0x02
+	cpu.V[3] = 0x10
+	cpu.ExecuteOp(0xB300)
+	if cpu.PC != 0x302 {
+		t.Errorf("VIP: pc %#x", cpu.PC)
+	}
+	if cpu.SP != 0 {
+		t.Log("jump pushed to the stack")
+		t.Fail()
+	}
+
+	cpu.Quirks = chip8.SCHIP11
+	cpu.ExecuteOp(0xB300)
+	if cpu.PC != 0x310 {
+		t.Errorf("SCHIP11: pc %#x", cpu.PC)
+	}
 }
 func TestOpCodeCXNN(t *testing.T) {
 	//cpu := chip8.NewCPU(nil)
 	t.Skip()
 }
 func TestOpCodeEX9E(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.V[1] = 0xA
+	cpu.ExecuteOp(0xE19E)
+	if cpu.PC != 0x202 {
+		t.Log("skipped without key pressed")
+		t.Fail()
+	}
+	cpu.Keypad.SetPressed(0xA, true)
+	cpu.ExecuteOp(0xE19E)
+	if cpu.PC != 0x206 {
+		t.Log("did not skip with key pressed")
+		t.Fail()
+	}
 }
 func TestOpCodeEXA1(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.V[1] = 0xA
+	cpu.ExecuteOp(0xE1A1)
+	if cpu.PC != 0x204 {
+		t.Log("did not skip without key pressed")
+		t.Fail()
+	}
+	cpu.Keypad.SetPressed(0xA, true)
+	cpu.ExecuteOp(0xE1A1)
+	if cpu.PC != 0x206 {
+		t.Log("skipped with key pressed")
+		t.Fail()
+	}
 }
 func TestOpCodeFX07(t *testing.T) {
 	//cpu := chip8.NewCPU(nil)
 	t.Skip()
 }
 func TestOpCodeFX0A(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.LoadData(0x200, []byte{0xF3, 0x0A})
+
+	cpu.Execute()
+	if cpu.PC != 0x200 {
+		t.Log("pc advanced without key press")
+		t.Fail()
+	}
+
+	cpu.Keypad.SetPressed(0x7, true)
+	cpu.Execute()
+	cpu.Execute()
+	if cpu.PC != 0x200 {
+		t.Log("pc advanced before key release")
+		t.Fail()
+	}
+
+	cpu.Keypad.SetPressed(0x7, false)
+	cpu.Execute()
+	if cpu.PC != 0x202 {
+		t.Log("pc not advanced after key release")
+		t.Fail()
+	}
+	if cpu.V[3] != 0x7 {
+		t.Errorf("v3 expected: %#x, actual: %#x", 0x7, cpu.V[3])
+	}
 }
 func TestOpCodeFX15(t *testing.T) {
 	//cpu := chip8.NewCPU(nil)
@@ -269,18 +431,252 @@ func TestOpCodeFX1E(t *testing.T) {
 	t.Skip()
 }
 func TestOpCodeFX29(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.V[2] = 0xF
+	cpu.ExecuteOp(0xF229)
+	if cpu.I != chip8.FontAddress+75 {
+		t.Errorf("I expected: %#x, actual: %#x", chip8.FontAddress+75, cpu.I)
+	}
+	if cpu.Memory[cpu.I] != chip8.DefaultFont[75] {
+		t.Log("font not loaded")
+		t.Fail()
+	}
+}
+func TestOpCodeFX30(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformSCHIP)
+	cpu.V[2] = 0x3
+	cpu.ExecuteOp(0xF230)
+	if cpu.I != chip8.LargeFontAddress+30 {
+		t.Errorf("I expected: %#x, actual: %#x", chip8.LargeFontAddress+30, cpu.I)
+	}
+	if cpu.Memory[cpu.I] != chip8.LargeFont[30] {
+		t.Log("large font not loaded")
+		t.Fail()
+	}
 }
 func TestOpCodeFX33(t *testing.T) {
 	//cpu := chip8.NewCPU(nil)
 	t.Skip()
 }
 func TestOpCodeFX55(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.Quirks = chip8.VIP
+	cpu.V = [16]byte{1, 2, 3, 4}
+	cpu.I = 0x300
+	cpu.ExecuteOp(0xF255)
+	if cpu.Memory[0x300] != 1 || cpu.Memory[0x301] != 2 || cpu.Memory[0x302] != 3 || cpu.Memory[0x303] != 0 {
+		t.Log("memory value not expected")
+		t.Fail()
+	}
+	if cpu.I != 0x303 {
+		t.Errorf("VIP: I %#x", cpu.I)
+	}
+
+	cpu.Quirks = chip8.CHIP48
+	cpu.I = 0x300
+	cpu.ExecuteOp(0xF255)
+	if cpu.I != 0x302 {
+		t.Errorf("CHIP48: I %#x", cpu.I)
+	}
+
+	cpu.Quirks = chip8.SCHIP11
+	cpu.I = 0x300
+	cpu.ExecuteOp(0xF255)
+	if cpu.I != 0x300 {
+		t.Errorf("SCHIP11: I %#x", cpu.I)
+	}
 }
 func TestOpCodeFX65(t *testing.T) {
-	//cpu := chip8.NewCPU(nil)
-	t.Skip()
+	cpu := chip8.NewCPU(nil)
+	cpu.Quirks = chip8.VIP
+	cpu.LoadData(0x300, []byte{5, 6, 7, 8})
+	cpu.I = 0x300
+	cpu.ExecuteOp(0xF265)
+	if cpu.V[0] != 5 || cpu.V[1] != 6 || cpu.V[2] != 7 || cpu.V[3] != 0 {
+		t.Log("register value not expected")
+		t.Fail()
+	}
+	if cpu.I != 0x303 {
+		t.Errorf("VIP: I %#x", cpu.I)
+	}
+
+	cpu.Quirks = chip8.CHIP48
+	cpu.I = 0x300
+	cpu.ExecuteOp(0xF265)
+	if cpu.I != 0x302 {
+		t.Errorf("CHIP48: I %#x", cpu.I)
+	}
+}
+
+func TestSCHIPResolution(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.ExecuteOp(0x00FF)
+	if cpu.Framebuffer.HiRes() {
+		t.Log("CHIP-8 switched to high resolution")
+		t.Fail()
+	}
+
+	cpu.SetPlatform(chip8.PlatformSCHIP)
+	cpu.ExecuteOp(0x00FF)
+	if !cpu.Framebuffer.HiRes() {
+		t.Log("00FF did not switch to high resolution")
+		t.Fail()
+	}
+	cpu.ExecuteOp(0x00FE)
+	if cpu.Framebuffer.HiRes() {
+		t.Log("00FE did not switch to low resolution")
+		t.Fail()
+	}
+}
+
+func TestSCHIPScroll(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformSCHIP)
+	cpu.Framebuffer.SetPixel(8, 8, true)
+	cpu.ExecuteOp(0x00C2)
+	cpu.ExecuteOp(0x00FB)
+	if !cpu.Framebuffer.Pixel(12, 10) {
+		t.Log("00CN/00FB did not scroll")
+		t.Fail()
+	}
+	cpu.ExecuteOp(0x00FC)
+	if !cpu.Framebuffer.Pixel(8, 10) {
+		t.Log("00FC did not scroll")
+		t.Fail()
+	}
+}
+
+func TestSCHIPExit(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformSCHIP)
+	cpu.ExecuteOp(0x00FD)
+	if !cpu.Finished {
+		t.Fail()
+	}
+}
+
+func TestSCHIPLargeSprite(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformSCHIP)
+	cpu.ExecuteOp(0x00FF)
+	sprite := make([]byte, 32)
+	for i := range sprite {
+		sprite[i] = 0xFF
+	}
+	cpu.LoadData(0x300, sprite)
+	cpu.I = 0x300
+	cpu.ExecuteOp(0xD010)
+	if !cpu.Framebuffer.Pixel(15, 15) || cpu.Framebuffer.Pixel(16, 15) || cpu.Framebuffer.Pixel(15, 16) {
+		t.Log("16x16 sprite not drawn")
+		t.Fail()
+	}
+}
+
+func TestSCHIPRPLFlags(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformSCHIP)
+	cpu.V[0], cpu.V[1], cpu.V[2] = 1, 2, 3
+	cpu.ExecuteOp(0xF175)
+	cpu.V[0], cpu.V[1], cpu.V[2] = 0, 0, 0
+	cpu.ExecuteOp(0xF285)
+	if cpu.V[0] != 1 || cpu.V[1] != 2 || cpu.V[2] != 0 {
+		t.Errorf("registers not restored from flags: %v", cpu.V[:3])
+	}
+}
+
+func TestXOCHIPMemory(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	if len(cpu.Memory) != 4096 {
+		t.Errorf("CHIP-8 memory: %d bytes", len(cpu.Memory))
+	}
+	cpu.SetPlatform(chip8.PlatformXOCHIP)
+	if len(cpu.Memory) != 65536 {
+		t.Errorf("XO-CHIP memory: %d bytes", len(cpu.Memory))
+	}
+	if cpu.Memory[chip8.FontAddress] != chip8.DefaultFont[0] {
+		t.Log("memory contents lost when resizing")
+		t.Fail()
+	}
+}
+
+func TestXOCHIPLongLoad(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformXOCHIP)
+	program := []byte{
+		0xF0, 0x00, 0xAB, 0xCD, //0x200 - set I to 0xABCD
+		0x30, 0x00, //0x204 - skip if v0 == 0
+		0xF0, 0x00, 0x12, 0x34, //0x206 - set I to 0x1234
+		0x60, 0x01, //0x20a - set v0 to 1
+	}
+	cpu.LoadData(0x200, program)
+	cpu.Execute()
+	if cpu.I != 0xABCD || cpu.PC != 0x204 {
+		t.Errorf("I: %#x, PC: %#x", cpu.I, cpu.PC)
+	}
+	cpu.Execute()
+	if cpu.PC != 0x20a {
+		t.Errorf("skip did not pass over long instruction, PC: %#x", cpu.PC)
+	}
+}
+
+func TestXOCHIPRegisterRange(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformXOCHIP)
+	cpu.I = 0x300
+	cpu.V[2], cpu.V[3], cpu.V[4] = 2, 3, 4
+	cpu.ExecuteOp(0x5242)
+	if cpu.Memory[0x300] != 2 || cpu.Memory[0x302] != 4 || cpu.I != 0x300 {
+		t.Log("5XY2 did not store the range")
+		t.Fail()
+	}
+
+	cpu.ExecuteOp(0x5A83)
+	if cpu.V[0xA] != 2 || cpu.V[0x9] != 3 || cpu.V[0x8] != 4 {
+		t.Errorf("5XY3 descending range: %v", cpu.V[8:11])
+	}
+}
+
+func TestXOCHIPPlanes(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformXOCHIP)
+	cpu.LoadData(0x300, []byte{0x80, 0xC0})
+	cpu.I = 0x300
+
+	//both planes: the first byte goes to plane 1, the second to plane 2
+	cpu.ExecuteOp(0xF301)
+	cpu.ExecuteOp(0xD001)
+	if cpu.Framebuffer.Colour(0, 0) != 3 || cpu.Framebuffer.Colour(1, 0) != 2 {
+		t.Errorf("colours: %d %d", cpu.Framebuffer.Colour(0, 0), cpu.Framebuffer.Colour(1, 0))
+	}
+
+	//clearing plane 2 leaves plane 1
+	cpu.ExecuteOp(0xF201)
+	cpu.ExecuteOp(0x00E0)
+	if cpu.Framebuffer.Colour(0, 0) != 1 || cpu.Framebuffer.Colour(1, 0) != 0 {
+		t.Log("00E0 cleared unselected plane")
+		t.Fail()
+	}
+}
+
+func TestXOCHIPAudio(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformXOCHIP)
+	if cpu.Pitch != chip8.DefaultPitch {
+		t.Fail()
+	}
+	pattern := []byte{0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x0F}
+	cpu.LoadData(0x400, pattern)
+	cpu.I = 0x400
+	cpu.ExecuteOp(0xF002)
+	if cpu.AudioPattern[15] != 0x0F {
+		t.Log("pattern not loaded")
+		t.Fail()
+	}
+	cpu.V[1] = 100
+	cpu.ExecuteOp(0xF13A)
+	if cpu.Pitch != 100 {
+		t.Log("pitch not set")
+		t.Fail()
+	}
 }
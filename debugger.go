@@ -0,0 +1,454 @@
+package chip8
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrBreakpoint is returned by Frame when a debugger stops the CPU before an instruction
+var ErrBreakpoint = errors.New("chip8: breakpoint")
+
+const debuggerHelp = `commands (numbers are hex, except counts):
+  s, step [n]            execute n instructions (default 1)
+  n, next                execute the next instruction, running subroutine calls to their return
+  c, continue            run until a breakpoint
+  p, pause               stop the running program
+  back                   undo the last instruction (needs rewind history)
+  b, break [addr]        set a breakpoint at addr, or list breakpoints
+  d, delete addr         delete the breakpoint at addr
+  r, regs                show the registers and stack
+  x, mem addr [n]        hexdump n bytes of memory from addr (default 64)
+  l, list [addr]         disassemble around addr (default PC)
+  set reg value          set v0-vF, I, PC, SP, DT or ST
+  poke addr byte...      write bytes to memory
+  q, quit                stop the program
+`
+
+// Debugger controls a CPU from commands read from a terminal. While paused, Run keeps
+// drawing the display without running frames, and executes commands as they arrive.
+type Debugger struct {
+	cpu *CPU
+	out io.Writer
+
+	//lines read from the terminal, closed at the end of its input
+	commands chan string
+
+	breakpoints map[uint16]bool
+
+	//stopWhen stops the CPU after a next command or an Interrupt
+	stopWhen func(c *CPU) bool
+
+	//after continuing, the instruction at resumedAt cycles runs even if it is a breakpoint
+	resumed   bool
+	resumedAt uint64
+
+	paused bool
+	quit   bool
+
+	//disassembly of the program for list, made on first use and again after a poke
+	disassembly *Disassembly
+
+	//Rewind provides the history for the back command, when set
+	Rewind *Rewind
+}
+
+// NewDebugger attaches a debugger to c, reading commands from in and writing to out
+func NewDebugger(c *CPU, in io.Reader, out io.Writer) *Debugger {
+	d := &Debugger{
+		cpu:         c,
+		out:         out,
+		commands:    make(chan string),
+		breakpoints: map[uint16]bool{},
+	}
+	c.Debugger = d
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			d.commands <- scanner.Text()
+		}
+		close(d.commands)
+	}()
+	return d
+}
+
+// Break sets a breakpoint on an address
+func (d *Debugger) Break(addr uint16) {
+	d.breakpoints[addr] = true
+}
+
+// Paused reports whether the debugger has stopped the CPU
+func (d *Debugger) Paused() bool {
+	return d.paused
+}
+
+// Quit reports whether the user asked to stop the program
+func (d *Debugger) Quit() bool {
+	return d.quit
+}
+
+// Pause stops the CPU, giving the reason and prompting for commands
+func (d *Debugger) Pause(reason string) {
+	if !d.paused && d.cpu.Sound != nil && d.cpu.beeping {
+		d.cpu.Sound.Stop(d.cpu.Frames)
+	}
+	d.paused = true
+	d.stopWhen = nil
+	if reason != "" {
+		fmt.Fprintln(d.out, reason)
+	}
+	d.printNext()
+	d.prompt()
+}
+
+// Interrupt stops the CPU before its next instruction
+func (d *Debugger) Interrupt() {
+	d.stopWhen = func(c *CPU) bool { return true }
+}
+
+// resume lets the CPU run until the next breakpoint
+func (d *Debugger) resume() {
+	if d.paused && d.cpu.Sound != nil && d.cpu.beeping {
+		d.cpu.Sound.Start(d.cpu.Frames)
+	}
+	d.paused = false
+	d.resumed, d.resumedAt = true, d.cpu.Cycles
+}
+
+// stopBefore reports whether the CPU should stop before the instruction at PC
+func (d *Debugger) stopBefore() bool {
+	c := d.cpu
+	if d.resumed && c.Cycles == d.resumedAt {
+		return false
+	}
+	return d.breakpoints[c.PC] || (d.stopWhen != nil && d.stopWhen(c))
+}
+
+// stopped pauses after Frame returned err
+func (d *Debugger) stopped(err error) {
+	switch {
+	case err != ErrBreakpoint:
+		d.Pause(err.Error())
+	case d.breakpoints[d.cpu.PC]:
+		d.Pause("breakpoint")
+	default:
+		d.Pause("")
+	}
+}
+
+// Poll executes the commands that have arrived, without waiting for more
+func (d *Debugger) Poll() {
+	for {
+		select {
+		case line, ok := <-d.commands:
+			if !ok {
+				d.quit = true
+				return
+			}
+			d.Exec(line)
+			if d.paused && !d.quit {
+				d.prompt()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (d *Debugger) prompt() {
+	fmt.Fprint(d.out, "(chip8) ")
+}
+
+// Exec executes a debugger command
+func (d *Debugger) Exec(line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	c := d.cpu
+	args := fields[1:]
+	switch fields[0] {
+	case "s", "step", "n", "next", "back":
+		if !d.paused {
+			fmt.Fprintln(d.out, "the program is running, pause it first")
+			return
+		}
+	}
+	switch fields[0] {
+	case "s", "step":
+		n := 1
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+				fmt.Fprintf(d.out, "bad count: %s\n", args[0])
+				return
+			}
+		}
+		for i := 0; i < n; i++ {
+			if err := c.Step(); err != nil {
+				fmt.Fprintln(d.out, err)
+				break
+			}
+		}
+		d.printNext()
+	case "n", "next":
+		inst := DecodeAt(c.Memory, int(c.PC), c.Platform)
+		if inst.Op != OpCall {
+			d.Exec("step")
+			return
+		}
+		ret, sp := c.PC+WordLength, c.SP
+		d.resume()
+		d.stopWhen = func(c *CPU) bool { return c.PC == ret && c.SP == sp }
+	case "c", "continue":
+		d.resume()
+	case "p", "pause":
+		if !d.paused {
+			d.Interrupt()
+		}
+	case "back":
+		if d.Rewind == nil {
+			fmt.Fprintln(d.out, "no rewind history")
+			return
+		}
+		if err := d.Rewind.StepBack(); err != nil {
+			fmt.Fprintln(d.out, err)
+		}
+		d.printNext()
+	case "b", "break":
+		if len(args) == 0 {
+			d.listBreakpoints()
+			return
+		}
+		if addr, ok := d.parse(args[0], 0xFFFF); ok {
+			d.Break(uint16(addr))
+		}
+	case "d", "delete":
+		if len(args) != 1 {
+			fmt.Fprintln(d.out, "usage: delete addr")
+			return
+		}
+		if addr, ok := d.parse(args[0], 0xFFFF); ok {
+			delete(d.breakpoints, uint16(addr))
+		}
+	case "r", "regs":
+		d.printRegisters()
+	case "x", "mem":
+		if len(args) == 0 {
+			fmt.Fprintln(d.out, "usage: mem addr [n]")
+			return
+		}
+		addr, ok := d.parse(args[0], len(c.Memory)-1)
+		n := 64
+		if ok && len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				fmt.Fprintf(d.out, "bad count: %s\n", args[1])
+				return
+			}
+		}
+		if ok {
+			d.hexdump(addr, n)
+		}
+	case "l", "list":
+		addr := int(c.PC)
+		if len(args) > 0 {
+			var ok bool
+			if addr, ok = d.parse(args[0], len(c.Memory)-1); !ok {
+				return
+			}
+		}
+		d.list(uint16(addr))
+	case "set":
+		if len(args) != 2 {
+			fmt.Fprintln(d.out, "usage: set reg value")
+			return
+		}
+		d.set(strings.ToUpper(args[0]), args[1])
+	case "poke":
+		if len(args) < 2 {
+			fmt.Fprintln(d.out, "usage: poke addr byte...")
+			return
+		}
+		addr, ok := d.parse(args[0], len(c.Memory)-len(args[1:]))
+		if !ok {
+			return
+		}
+		for i, arg := range args[1:] {
+			value, ok := d.parse(arg, 0xFF)
+			if !ok {
+				return
+			}
+			c.Memory[addr+i] = byte(value)
+			d.disassembly = nil
+		}
+	case "q", "quit":
+		d.quit = true
+	case "h", "help", "?":
+		fmt.Fprint(d.out, debuggerHelp)
+	default:
+		fmt.Fprintf(d.out, "unknown command %q, try help\n", fields[0])
+	}
+}
+
+// parse reads a hex number no greater than max
+func (d *Debugger) parse(s string, max int) (int, bool) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 32)
+	if err != nil || int(value) > max {
+		fmt.Fprintf(d.out, "bad value %s: expected hex up to %#x\n", s, max)
+		return 0, false
+	}
+	return int(value), true
+}
+
+// set changes a register
+func (d *Debugger) set(reg, s string) {
+	c := d.cpu
+	switch {
+	case len(reg) == 2 && reg[0] == 'V':
+		x, err := strconv.ParseUint(reg[1:], 16, 8)
+		if err != nil {
+			break
+		}
+		if value, ok := d.parse(s, 0xFF); ok {
+			c.V[x] = byte(value)
+		}
+		return
+	case reg == "I" || reg == "PC":
+		value, ok := d.parse(s, 0xFFFF)
+		if !ok {
+			return
+		}
+		if reg == "I" {
+			c.I = uint16(value)
+		} else {
+			c.PC = uint16(value)
+		}
+		return
+	case reg == "SP":
+		if value, ok := d.parse(s, len(c.Stack)); ok {
+			c.SP = byte(value)
+		}
+		return
+	case reg == "DT" || reg == "ST":
+		value, ok := d.parse(s, 0xFF)
+		if !ok {
+			return
+		}
+		if reg == "DT" {
+			c.DT = byte(value)
+		} else {
+			c.ST = byte(value)
+			c.updateSound()
+		}
+		return
+	}
+	fmt.Fprintf(d.out, "unknown register %s\n", reg)
+}
+
+// printNext shows the instruction at PC
+func (d *Debugger) printNext() {
+	inst := DecodeAt(d.cpu.Memory, int(d.cpu.PC), d.cpu.Platform)
+	fmt.Fprintf(d.out, "=> %#04x  %s\n", d.cpu.PC, inst)
+}
+
+func (d *Debugger) printRegisters() {
+	c := d.cpu
+	for x, v := range c.V {
+		fmt.Fprintf(d.out, "V%X %02X", x, v)
+		if x%8 == 7 {
+			fmt.Fprintln(d.out)
+		} else {
+			fmt.Fprint(d.out, "  ")
+		}
+	}
+	fmt.Fprintf(d.out, "I %#04x  PC %#04x  SP %d  DT %d  ST %d  frame %d  cycle %d\n", c.I, c.PC, c.SP, c.DT, c.ST, c.Frames, c.Cycles)
+	fmt.Fprint(d.out, "stack:")
+	for _, addr := range c.Stack[:c.SP] {
+		fmt.Fprintf(d.out, " %#04x", addr)
+	}
+	fmt.Fprintln(d.out)
+}
+
+func (d *Debugger) listBreakpoints() {
+	addrs := make([]int, 0, len(d.breakpoints))
+	for addr := range d.breakpoints {
+		addrs = append(addrs, int(addr))
+	}
+	sort.Ints(addrs)
+	for _, addr := range addrs {
+		fmt.Fprintf(d.out, "%#04x\n", addr)
+	}
+}
+
+// hexdump writes n bytes of memory from addr, 16 to a line
+func (d *Debugger) hexdump(addr, n int) {
+	memory := d.cpu.Memory
+	if addr+n > len(memory) {
+		n = len(memory) - addr
+	}
+	for line := addr; line < addr+n; line += 16 {
+		end := line + 16
+		if end > addr+n {
+			end = addr + n
+		}
+		hex, text := "", ""
+		for i := line; i < end; i++ {
+			hex += fmt.Sprintf("%02X ", memory[i])
+			if memory[i] >= 0x20 && memory[i] < 0x7F {
+				text += string(rune(memory[i]))
+			} else {
+				text += "."
+			}
+		}
+		fmt.Fprintf(d.out, "%#04x  %-48s %s\n", line, hex, text)
+	}
+}
+
+// list disassembles the instructions around addr, with the labels of the flow-following
+// disassembly of the program. PC is marked with => and breakpoints with *.
+func (d *Debugger) list(addr uint16) {
+	const before, after = 5, 10
+	c := d.cpu
+	if d.disassembly == nil && len(c.Memory) > ProgramAddress {
+		d.disassembly = Disassemble(c.Memory[ProgramAddress:], c.Platform)
+	}
+	dis := d.disassembly
+
+	//back up over the instructions the disassembler found before addr
+	start := int(addr)
+	if dis != nil && dis.IsCode(start) {
+		found := 0
+		for a := start - 1; a >= ProgramAddress && a > int(addr)-8*before && found < before; a-- {
+			if dis.IsCode(a) {
+				start = a
+				found++
+			}
+		}
+	}
+
+	a := start
+	for n := 0; n < before+after && a < len(c.Memory); n++ {
+		inst := DecodeAt(c.Memory, a, c.Platform)
+		text := inst.String()
+		if dis != nil {
+			if name, ok := dis.Label(uint16(a)); ok {
+				fmt.Fprintf(d.out, "%s:\n", name)
+			}
+			text = dis.instruction(&inst)
+		}
+		marker := "  "
+		switch {
+		case a == int(c.PC):
+			marker = "=>"
+		case d.breakpoints[uint16(a)]:
+			marker = " *"
+		}
+		fmt.Fprintf(d.out, "%s %#04x  %04X  %s\n", marker, a, inst.Opcode, text)
+		a += inst.Length
+	}
+}
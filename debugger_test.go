@@ -0,0 +1,124 @@
+package chip8_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alisdairrankine/chip8"
+	"github.com/alisdairrankine/chip8/asm"
+)
+
+// counts calls of a subroutine in v0
+const caller = `
+loop:
+	SBR count
+	JMP loop
+count:
+	ADD v0,1
+	RTN
+`
+
+// newCaller loads caller with a debugger that is never sent commands
+func newCaller(t *testing.T) (*chip8.CPU, *chip8.Debugger, *bytes.Buffer) {
+	program, err := asm.Assemble("caller.asm", []byte(caller))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := chip8.NewCPU(nil)
+	cpu.LoadProgram(program)
+	in, _ := io.Pipe()
+	var out bytes.Buffer
+	return cpu, chip8.NewDebugger(cpu, in, &out), &out
+}
+
+// closeAfter is a display that closes after a number of frames
+type closeAfter struct {
+	*chip8.HeadlessDisplay
+	frames int
+}
+
+func (d *closeAfter) Closed() bool {
+	return d.FrameCount() >= d.frames
+}
+
+func TestDebuggerPausesRun(t *testing.T) {
+	cpu, debugger, out := newCaller(t)
+	clock := make(chan time.Time, 10)
+	for i := 0; i < cap(clock); i++ {
+		clock <- time.Time{}
+	}
+	cpu.Clock = clock
+	debugger.Break(0x206)
+
+	display := &closeAfter{HeadlessDisplay: chip8.NewHeadlessDisplay(1), frames: cap(clock)}
+	if err := cpu.Run(display); err != nil {
+		t.Fatal(err)
+	}
+	if !debugger.Paused() || cpu.PC != 0x206 || cpu.Cycles != 2 {
+		t.Errorf("paused %v at 0x%03X after %d cycles", debugger.Paused(), cpu.PC, cpu.Cycles)
+	}
+	//the display is still drawn while paused
+	if display.FrameCount() != cap(clock) {
+		t.Errorf("%d frames drawn", display.FrameCount())
+	}
+	if !strings.Contains(out.String(), "breakpoint\n=> 0x0206  RTN\n") {
+		t.Errorf("breakpoint reported as %q", out.String())
+	}
+}
+
+func TestDebuggerCommands(t *testing.T) {
+	cpu, debugger, out := newCaller(t)
+	debugger.Interrupt()
+	if err := cpu.Frame(); err != chip8.ErrBreakpoint || cpu.Cycles != 0 {
+		t.Fatalf("interrupted with %v after %d cycles", err, cpu.Cycles)
+	}
+	debugger.Pause("")
+
+	//next runs the subroutine and stops at the return address
+	debugger.Exec("next")
+	if err := cpu.Frame(); err != chip8.ErrBreakpoint || cpu.PC != 0x202 || cpu.V[0] != 1 {
+		t.Fatalf("next stopped with %v at 0x%03X, v0=%d", err, cpu.PC, cpu.V[0])
+	}
+	debugger.Pause("")
+	debugger.Exec("step 2")
+	if cpu.PC != 0x204 || cpu.SP != 1 {
+		t.Errorf("stepped to 0x%03X with SP %d", cpu.PC, cpu.SP)
+	}
+
+	debugger.Exec("set v3 2a")
+	debugger.Exec("set I 300")
+	debugger.Exec("poke 300 aa bb")
+	if cpu.V[3] != 0x2A || cpu.I != 0x300 || cpu.Memory[0x300] != 0xAA || cpu.Memory[0x301] != 0xBB {
+		t.Error("set and poke did not change the machine")
+	}
+
+	out.Reset()
+	debugger.Exec("regs")
+	debugger.Exec("mem 300 2")
+	debugger.Exec("list")
+	for _, want := range []string{"V3 2A", "stack: 0x0200", "0x0300  AA BB", "sub_204:\n=> 0x0204  7001  ADD v0,0x1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	//a poke is disassembled again, here calling into the middle of the subroutine
+	debugger.Exec("poke 200 22 06")
+	out.Reset()
+	debugger.Exec("list")
+	if !strings.Contains(out.String(), "sub_206:\n   0x0206") {
+		t.Errorf("list after poke:\n%s", out.String())
+	}
+	debugger.Exec("poke 200 22 04")
+
+	//continuing from a breakpoint runs it before stopping there again
+	debugger.Exec("break 204")
+	debugger.Exec("continue")
+	cycles := cpu.Cycles
+	if err := cpu.Frame(); err != chip8.ErrBreakpoint || cpu.PC != 0x204 || cpu.Cycles != cycles+4 {
+		t.Errorf("continued to 0x%03X after %d cycles, with %v", cpu.PC, cpu.Cycles-cycles, err)
+	}
+}
@@ -0,0 +1,484 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op identifies an operation, independently of its operands
+type Op int
+
+const (
+	OpInvalid Op = iota
+
+	OpSys //0NNN
+	OpClear
+	OpReturn
+	OpScrollDown  //00CN, SUPER-CHIP
+	OpScrollUp    //00DN, XO-CHIP
+	OpScrollRight //00FB, SUPER-CHIP
+	OpScrollLeft  //00FC, SUPER-CHIP
+	OpExit        //00FD, SUPER-CHIP
+	OpLowRes      //00FE, SUPER-CHIP
+	OpHighRes     //00FF, SUPER-CHIP
+	OpJump
+	OpCall
+	OpSkipEqualImm    //3XNN
+	OpSkipNotEqualImm //4XNN
+	OpSkipEqual       //5XY0
+	OpSaveRange       //5XY2, XO-CHIP
+	OpLoadRange       //5XY3, XO-CHIP
+	OpSetImm          //6XNN
+	OpAddImm          //7XNN
+	OpSet             //8XY0
+	OpOr
+	OpAnd
+	OpXor
+	OpAdd
+	OpSub
+	OpShiftRight
+	OpSubReverse //8XY7
+	OpShiftLeft
+	OpSkipNotEqual //9XY0
+	OpSetI
+	OpJumpOffset
+	OpRandom
+	OpDraw
+	OpSkipKey
+	OpSkipNotKey
+	OpSetILong //F000 NNNN, XO-CHIP
+	OpPlanes   //FN01, XO-CHIP
+	OpAudio    //F002, XO-CHIP
+	OpGetDelay
+	OpWaitKey
+	OpSetDelay
+	OpSetSound
+	OpAddI
+	OpFont
+	OpLargeFont //FX30, SUPER-CHIP
+	OpBCD
+	OpPitch //FX3A, XO-CHIP
+	OpStore
+	OpLoad
+	OpSaveFlags //FX75, SUPER-CHIP
+	OpLoadFlags //FX85, SUPER-CHIP
+
+	opCount
+)
+
+// Category groups operations by what they do, as in the opcode tables of the README
+type Category int
+
+const (
+	CategoryInvalid Category = iota
+	CategoryCall
+	CategoryDisplay
+	CategoryFlow
+	CategoryCondition
+	CategoryConstant
+	CategoryAssign
+	CategoryBitOp
+	CategoryMath
+	CategoryMemory
+	CategoryRandom
+	CategoryKey
+	CategoryTimer
+	CategorySound
+	CategoryBCD
+)
+
+func (c Category) String() string {
+	switch c {
+	case CategoryCall:
+		return "Call"
+	case CategoryDisplay:
+		return "Display"
+	case CategoryFlow:
+		return "Flow"
+	case CategoryCondition:
+		return "Cond"
+	case CategoryConstant:
+		return "Const"
+	case CategoryAssign:
+		return "Assign"
+	case CategoryBitOp:
+		return "BitOp"
+	case CategoryMath:
+		return "Math"
+	case CategoryMemory:
+		return "MEM"
+	case CategoryRandom:
+		return "Rand"
+	case CategoryKey:
+		return "KeyOp"
+	case CategoryTimer:
+		return "Timer"
+	case CategorySound:
+		return "Sound"
+	case CategoryBCD:
+		return "BCD"
+	}
+	return "Invalid"
+}
+
+// OpInfo describes how an operation is encoded and written
+type OpInfo struct {
+	Mnemonic string
+
+	//Operands is the comma separated operand template. vX and vY are registers, N, NN, NNN
+	//and NNNN are numbers taken from those opcode fields, X is a number taken from the X field,
+	//and anything else (I, DT, ST) is written literally.
+	Operands string
+
+	//Pattern is the opcode with every operand field zero
+	Pattern uint16
+
+	Category Category
+
+	//Platform is the first platform to define the operation
+	Platform Platform
+}
+
+var opInfo = [opCount]OpInfo{
+	OpInvalid:         {"!!!", "", 0, CategoryInvalid, PlatformCHIP8},
+	OpSys:             {"RCA", "NNN", 0x0000, CategoryCall, PlatformCHIP8},
+	OpClear:           {"CLS", "", 0x00E0, CategoryDisplay, PlatformCHIP8},
+	OpReturn:          {"RTN", "", 0x00EE, CategoryFlow, PlatformCHIP8},
+	OpScrollDown:      {"SCD", "N", 0x00C0, CategoryDisplay, PlatformSCHIP},
+	OpScrollUp:        {"SCU", "N", 0x00D0, CategoryDisplay, PlatformXOCHIP},
+	OpScrollRight:     {"SCR", "", 0x00FB, CategoryDisplay, PlatformSCHIP},
+	OpScrollLeft:      {"SCL", "", 0x00FC, CategoryDisplay, PlatformSCHIP},
+	OpExit:            {"EXT", "", 0x00FD, CategoryFlow, PlatformSCHIP},
+	OpLowRes:          {"LOW", "", 0x00FE, CategoryDisplay, PlatformSCHIP},
+	OpHighRes:         {"HGH", "", 0x00FF, CategoryDisplay, PlatformSCHIP},
+	OpJump:            {"JMP", "NNN", 0x1000, CategoryFlow, PlatformCHIP8},
+	OpCall:            {"SBR", "NNN", 0x2000, CategoryFlow, PlatformCHIP8},
+	OpSkipEqualImm:    {"JEQ", "vX,NN", 0x3000, CategoryCondition, PlatformCHIP8},
+	OpSkipNotEqualImm: {"JNE", "vX,NN", 0x4000, CategoryCondition, PlatformCHIP8},
+	OpSkipEqual:       {"JEQ", "vX,vY", 0x5000, CategoryCondition, PlatformCHIP8},
+	OpSaveRange:       {"SVR", "vX,vY", 0x5002, CategoryMemory, PlatformXOCHIP},
+	OpLoadRange:       {"LDR", "vX,vY", 0x5003, CategoryMemory, PlatformXOCHIP},
+	OpSetImm:          {"SET", "vX,NN", 0x6000, CategoryConstant, PlatformCHIP8},
+	OpAddImm:          {"ADD", "vX,NN", 0x7000, CategoryConstant, PlatformCHIP8},
+	OpSet:             {"SET", "vX,vY", 0x8000, CategoryAssign, PlatformCHIP8},
+	OpOr:              {"OR", "vX,vY", 0x8001, CategoryBitOp, PlatformCHIP8},
+	OpAnd:             {"AND", "vX,vY", 0x8002, CategoryBitOp, PlatformCHIP8},
+	OpXor:             {"XOR", "vX,vY", 0x8003, CategoryBitOp, PlatformCHIP8},
+	OpAdd:             {"ADD", "vX,vY", 0x8004, CategoryMath, PlatformCHIP8},
+	OpSub:             {"SUB", "vX,vY", 0x8005, CategoryMath, PlatformCHIP8},
+	OpShiftRight:      {"BSR", "vX,vY", 0x8006, CategoryBitOp, PlatformCHIP8},
+	OpSubReverse:      {"RSB", "vX,vY", 0x8007, CategoryMath, PlatformCHIP8},
+	OpShiftLeft:       {"BSL", "vX,vY", 0x800E, CategoryBitOp, PlatformCHIP8},
+	OpSkipNotEqual:    {"JNE", "vX,vY", 0x9000, CategoryCondition, PlatformCHIP8},
+	OpSetI:            {"ADR", "NNN", 0xA000, CategoryMemory, PlatformCHIP8},
+	OpJumpOffset:      {"JMA", "NNN", 0xB000, CategoryFlow, PlatformCHIP8},
+	OpRandom:          {"RND", "vX,NN", 0xC000, CategoryRandom, PlatformCHIP8},
+	OpDraw:            {"DRW", "vX,vY,N", 0xD000, CategoryDisplay, PlatformCHIP8},
+	OpSkipKey:         {"JKP", "vX", 0xE09E, CategoryKey, PlatformCHIP8},
+	OpSkipNotKey:      {"JKN", "vX", 0xE0A1, CategoryKey, PlatformCHIP8},
+	OpSetILong:        {"ADL", "NNNN", 0xF000, CategoryMemory, PlatformXOCHIP},
+	OpPlanes:          {"PLN", "X", 0xF001, CategoryDisplay, PlatformXOCHIP},
+	OpAudio:           {"AUD", "", 0xF002, CategorySound, PlatformXOCHIP},
+	OpGetDelay:        {"SET", "vX,DT", 0xF007, CategoryTimer, PlatformCHIP8},
+	OpWaitKey:         {"WKP", "vX", 0xF00A, CategoryKey, PlatformCHIP8},
+	OpSetDelay:        {"SET", "DT,vX", 0xF015, CategoryTimer, PlatformCHIP8},
+	OpSetSound:        {"SET", "ST,vX", 0xF018, CategorySound, PlatformCHIP8},
+	OpAddI:            {"ADD", "I,vX", 0xF01E, CategoryMemory, PlatformCHIP8},
+	OpFont:            {"FNT", "vX", 0xF029, CategoryMemory, PlatformCHIP8},
+	OpLargeFont:       {"HFN", "vX", 0xF030, CategoryMemory, PlatformSCHIP},
+	OpBCD:             {"BCD", "vX", 0xF033, CategoryBCD, PlatformCHIP8},
+	OpPitch:           {"PIT", "vX", 0xF03A, CategorySound, PlatformXOCHIP},
+	OpStore:           {"DMP", "vX", 0xF055, CategoryMemory, PlatformCHIP8},
+	OpLoad:            {"LOD", "vX", 0xF065, CategoryMemory, PlatformCHIP8},
+	OpSaveFlags:       {"SRP", "vX", 0xF075, CategoryMemory, PlatformSCHIP},
+	OpLoadFlags:       {"LRP", "vX", 0xF085, CategoryMemory, PlatformSCHIP},
+}
+
+// Info describes the encoding and syntax of the operation
+func (op Op) Info() OpInfo {
+	if op < 0 || op >= opCount {
+		return opInfo[OpInvalid]
+	}
+	return opInfo[op]
+}
+
+// Ops lists every valid operation
+func Ops() []Op {
+	ops := make([]Op, 0, opCount-1)
+	for op := OpInvalid + 1; op < opCount; op++ {
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Instruction is a decoded opcode
+type Instruction struct {
+	Op       Op       `json:"op"`
+	Opcode   uint16   `json:"opcode"`
+	Mnemonic string   `json:"mnemonic"`
+	Category Category `json:"category"`
+
+	//operand fields of the opcode, whether or not the operation uses them
+	X   byte   `json:"x"`
+	Y   byte   `json:"y"`
+	N   byte   `json:"n"`
+	NN  byte   `json:"nn"`
+	NNN uint16 `json:"nnn"`
+
+	//address following a long instruction (F000 NNNN), filled in by DecodeAt
+	NNNN uint16 `json:"nnnn,omitempty"`
+
+	//Length in bytes: WordLength, or twice that for F000 NNNN
+	Length int `json:"length"`
+}
+
+// Decode splits an opcode into its operation and operand fields. Opcodes the platform does
+// not define decode as OpInvalid, except that the 0NNN machine code calls are always valid.
+func Decode(opCode uint16, platform Platform) Instruction {
+	op := decodeOp(opCode, platform)
+	info := opInfo[op]
+	inst := Instruction{
+		Op:       op,
+		Opcode:   opCode,
+		Mnemonic: info.Mnemonic,
+		Category: info.Category,
+		X:        byte(opCode >> 8 & 0x0F),
+		Y:        byte(opCode >> 4 & 0x0F),
+		N:        byte(opCode & 0x0F),
+		NN:       byte(opCode),
+		NNN:      opCode & 0x0FFF,
+		Length:   WordLength,
+	}
+	if op == OpSetILong {
+		inst.Length = 2 * WordLength
+	}
+	return inst
+}
+
+// DecodeAt decodes the instruction at addr, reading the address that follows a long
+// instruction. Words past the end of memory read as zero.
+func DecodeAt(memory []byte, addr int, platform Platform) Instruction {
+	word := func(a int) uint16 {
+		if a < 0 || a+1 >= len(memory) {
+			return 0
+		}
+		return uint16(memory[a])<<8 | uint16(memory[a+1])
+	}
+	inst := Decode(word(addr), platform)
+	if inst.Length > WordLength {
+		inst.NNNN = word(addr + WordLength)
+	}
+	return inst
+}
+
+func decodeOp(opCode uint16, platform Platform) Op {
+	schip := platform >= PlatformSCHIP
+	xochip := platform >= PlatformXOCHIP
+	switch opCode & 0xF000 {
+	case 0x0000:
+		switch {
+		case opCode == 0x00E0:
+			return OpClear
+		case opCode == 0x00EE:
+			return OpReturn
+		case opCode&0xFFF0 == 0x00C0 && schip:
+			return OpScrollDown
+		case opCode&0xFFF0 == 0x00D0 && xochip:
+			return OpScrollUp
+		case opCode == 0x00FB && schip:
+			return OpScrollRight
+		case opCode == 0x00FC && schip:
+			return OpScrollLeft
+		case opCode == 0x00FD && schip:
+			return OpExit
+		case opCode == 0x00FE && schip:
+			return OpLowRes
+		case opCode == 0x00FF && schip:
+			return OpHighRes
+		}
+		return OpSys
+	case 0x1000:
+		return OpJump
+	case 0x2000:
+		return OpCall
+	case 0x3000:
+		return OpSkipEqualImm
+	case 0x4000:
+		return OpSkipNotEqualImm
+	case 0x5000:
+		switch {
+		case opCode&0x000F == 0x0:
+			return OpSkipEqual
+		case opCode&0x000F == 0x2 && xochip:
+			return OpSaveRange
+		case opCode&0x000F == 0x3 && xochip:
+			return OpLoadRange
+		}
+	case 0x6000:
+		return OpSetImm
+	case 0x7000:
+		return OpAddImm
+	case 0x8000:
+		switch opCode & 0x000F {
+		case 0x0:
+			return OpSet
+		case 0x1:
+			return OpOr
+		case 0x2:
+			return OpAnd
+		case 0x3:
+			return OpXor
+		case 0x4:
+			return OpAdd
+		case 0x5:
+			return OpSub
+		case 0x6:
+			return OpShiftRight
+		case 0x7:
+			return OpSubReverse
+		case 0xE:
+			return OpShiftLeft
+		}
+	case 0x9000:
+		if opCode&0x000F == 0 {
+			return OpSkipNotEqual
+		}
+	case 0xA000:
+		return OpSetI
+	case 0xB000:
+		return OpJumpOffset
+	case 0xC000:
+		return OpRandom
+	case 0xD000:
+		return OpDraw
+	case 0xE000:
+		switch opCode & 0x00FF {
+		case 0x9E:
+			return OpSkipKey
+		case 0xA1:
+			return OpSkipNotKey
+		}
+	case 0xF000:
+		switch {
+		case opCode == 0xF000 && xochip:
+			return OpSetILong
+		case opCode&0x00FF == 0x01 && xochip:
+			return OpPlanes
+		case opCode == 0xF002 && xochip:
+			return OpAudio
+		}
+		switch opCode & 0x00FF {
+		case 0x07:
+			return OpGetDelay
+		case 0x0A:
+			return OpWaitKey
+		case 0x15:
+			return OpSetDelay
+		case 0x18:
+			return OpSetSound
+		case 0x1E:
+			return OpAddI
+		case 0x29:
+			return OpFont
+		case 0x30:
+			if schip {
+				return OpLargeFont
+			}
+		case 0x33:
+			return OpBCD
+		case 0x3A:
+			if xochip {
+				return OpPitch
+			}
+		case 0x55:
+			return OpStore
+		case 0x65:
+			return OpLoad
+		case 0x75:
+			if schip {
+				return OpSaveFlags
+			}
+		case 0x85:
+			if schip {
+				return OpLoadFlags
+			}
+		}
+	}
+	return OpInvalid
+}
+
+// Mask returns the bits of the opcode that identify the operation, as opposed to its operands
+func (info OpInfo) Mask() uint16 {
+	mask := uint16(0xFFFF)
+	for _, operand := range strings.Split(info.Operands, ",") {
+		switch operand {
+		case "vX", "X":
+			mask &^= 0x0F00
+		case "vY":
+			mask &^= 0x00F0
+		case "N":
+			mask &^= 0x000F
+		case "NN":
+			mask &^= 0x00FF
+		case "NNN":
+			mask &^= 0x0FFF
+		}
+	}
+	return mask
+}
+
+// Encode assembles the instruction's opcode from its operation and the operand fields the
+// operation uses
+func (i Instruction) Encode() uint16 {
+	info := i.Op.Info()
+	opCode := info.Pattern
+	for _, operand := range strings.Split(info.Operands, ",") {
+		switch operand {
+		case "vX", "X":
+			opCode |= uint16(i.X&0x0F) << 8
+		case "vY":
+			opCode |= uint16(i.Y&0x0F) << 4
+		case "N":
+			opCode |= uint16(i.N & 0x0F)
+		case "NN":
+			opCode |= uint16(i.NN)
+		case "NNN":
+			opCode |= i.NNN & 0x0FFF
+		}
+	}
+	if i.Op == OpInvalid {
+		return i.Opcode
+	}
+	return opCode
+}
+
+// String writes the instruction as assembly, e.g. "DRW v1,v2,0x5"
+func (i Instruction) String() string {
+	info := i.Op.Info()
+	if i.Op == OpInvalid {
+		return fmt.Sprintf("%s %#x", info.Mnemonic, i.Opcode)
+	}
+	if info.Operands == "" {
+		return info.Mnemonic
+	}
+	operands := strings.Split(info.Operands, ",")
+	for n, operand := range operands {
+		switch operand {
+		case "vX":
+			operands[n] = fmt.Sprintf("v%X", i.X)
+		case "vY":
+			operands[n] = fmt.Sprintf("v%X", i.Y)
+		case "X":
+			operands[n] = fmt.Sprintf("%d", i.X)
+		case "N":
+			operands[n] = fmt.Sprintf("%#x", i.N)
+		case "NN":
+			operands[n] = fmt.Sprintf("%#x", i.NN)
+		case "NNN":
+			operands[n] = fmt.Sprintf("%#x", i.NNN)
+		case "NNNN":
+			operands[n] = fmt.Sprintf("%#x", i.NNNN)
+		}
+	}
+	return info.Mnemonic + " " + strings.Join(operands, ",")
+}
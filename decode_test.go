@@ -0,0 +1,102 @@
+package chip8_test
+
+import (
+	"errors"
+	"math/bits"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+var platforms = []chip8.Platform{chip8.PlatformCHIP8, chip8.PlatformSCHIP, chip8.PlatformXOCHIP}
+
+// expectedOp finds the operation whose pattern matches opCode most specifically
+func expectedOp(opCode uint16, platform chip8.Platform) chip8.Op {
+	expected, bestBits := chip8.OpInvalid, -1
+	for _, op := range chip8.Ops() {
+		info := op.Info()
+		if info.Platform > platform || opCode&info.Mask() != info.Pattern {
+			continue
+		}
+		if n := bits.OnesCount16(info.Mask()); n > bestBits {
+			expected, bestBits = op, n
+		}
+	}
+	return expected
+}
+
+func TestDecodeAllOpcodes(t *testing.T) {
+	for _, platform := range platforms {
+		cpu := chip8.NewCPU(nil)
+		cpu.SetPlatform(platform)
+		failures := 0
+		for op := 0; op <= 0xFFFF && failures < 10; op++ {
+			opCode := uint16(op)
+			inst := chip8.Decode(opCode, platform)
+
+			if expected := expectedOp(opCode, platform); inst.Op != expected {
+				t.Errorf("%s %04X: decoded %s, expected %s", platform, opCode, inst.Op.Info().Mnemonic, expected.Info().Mnemonic)
+				failures++
+				continue
+			}
+			if inst.Mnemonic != inst.Op.Info().Mnemonic || inst.Category != inst.Op.Info().Category {
+				t.Errorf("%s %04X: inconsistent %+v", platform, opCode, inst)
+				failures++
+			}
+			if inst.Op != chip8.OpInvalid && inst.Encode() != opCode {
+				t.Errorf("%s %04X: encoded back to %04X", platform, opCode, inst.Encode())
+				failures++
+			}
+			if (inst.Length == 4) != (inst.Op == chip8.OpSetILong) {
+				t.Errorf("%s %04X: length %d", platform, opCode, inst.Length)
+				failures++
+			}
+
+			//the interpreter rejects exactly the opcodes that do not decode
+			cpu.PC, cpu.I, cpu.SP = 0x200, 0x300, 1
+			var invalid chip8.ErrInvalidOpcode
+			if rejected := errors.As(cpu.ExecuteOp(opCode), &invalid); rejected != (inst.Op == chip8.OpInvalid) {
+				t.Errorf("%s %04X: decoded as %s but interpreter rejected=%v", platform, opCode, inst, rejected)
+				failures++
+			}
+		}
+	}
+}
+
+func TestInstructionString(t *testing.T) {
+	tests := []struct {
+		opCode   uint16
+		platform chip8.Platform
+		expected string
+	}{
+		{0x00E0, chip8.PlatformCHIP8, "CLS"},
+		{0x0123, chip8.PlatformCHIP8, "RCA 0x123"},
+		{0x00FF, chip8.PlatformCHIP8, "RCA 0xff"},
+		{0x00FF, chip8.PlatformSCHIP, "HGH"},
+		{0x00C4, chip8.PlatformSCHIP, "SCD 0x4"},
+		{0x1234, chip8.PlatformCHIP8, "JMP 0x234"},
+		{0x3A12, chip8.PlatformCHIP8, "JEQ vA,0x12"},
+		{0x5AB0, chip8.PlatformCHIP8, "JEQ vA,vB"},
+		{0x5AB2, chip8.PlatformCHIP8, "!!! 0x5ab2"},
+		{0x5AB2, chip8.PlatformXOCHIP, "SVR vA,vB"},
+		{0x8AB5, chip8.PlatformCHIP8, "SUB vA,vB"},
+		{0x8AB7, chip8.PlatformCHIP8, "RSB vA,vB"},
+		{0xD125, chip8.PlatformCHIP8, "DRW v1,v2,0x5"},
+		{0xF31E, chip8.PlatformCHIP8, "ADD I,v3"},
+		{0xF355, chip8.PlatformCHIP8, "DMP v3"},
+		{0xF365, chip8.PlatformCHIP8, "LOD v3"},
+		{0xF307, chip8.PlatformCHIP8, "SET v3,DT"},
+		{0xF315, chip8.PlatformCHIP8, "SET DT,v3"},
+		{0xF201, chip8.PlatformXOCHIP, "PLN 2"},
+	}
+	for _, test := range tests {
+		if s := chip8.Decode(test.opCode, test.platform).String(); s != test.expected {
+			t.Errorf("%04X on %s: expected %q, got %q", test.opCode, test.platform, test.expected, s)
+		}
+	}
+
+	long := chip8.DecodeAt([]byte{0xF0, 0x00, 0x12, 0x34}, 0, chip8.PlatformXOCHIP)
+	if long.Length != 4 || long.NNNN != 0x1234 || long.String() != "ADL 0x1234" {
+		t.Errorf("unexpected long instruction %+v", long)
+	}
+}
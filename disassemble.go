@@ -1,193 +1,407 @@
 package chip8
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
+// Disassembly is a program split into the code reachable from ProgramAddress and the data
+// around it. Code is found by following jumps, calls and skips; everything else is data.
+type Disassembly struct {
+	Program  []byte
+	Platform Platform
+
+	//instructions by program offset, nil where no instruction starts
+	code []*Instruction
+
+	//bytes belonging to an instruction
+	covered []bool
+
+	//generated names of jump, call and data targets, by address
+	labels map[uint16]string
+
+	//bytes per row of the sprites drawn from data, by the program offset of each row
+	spriteRows map[int]int
+
+	//sprite tables found while following code, drawn from I indexed from their address
+	spriteTables []sprite
+
+	//state at the first return from each subroutine, by address
+	exits map[int]flowState
+}
+
+// sprite is the address and row width of sprite data, with the number of rows if known
+type sprite struct {
+	addr     uint16
+	rowBytes int
+	rows     int
+}
+
+// what the disassembler knows about I while following code
+const (
+	iUnknown = iota
+	//I holds an address loaded by ADR or ADL
+	iExact
+	//I has been moved on from such an address, as when indexing a table of sprites
+	iIndexed
+)
+
+// flowState is what the disassembler knows about the CPU while following code
+type flowState struct {
+	addr int
+
+	//entry point of the subroutine being followed
+	sub int
+
+	iState int
+	i      uint16
+}
+
+// Disassemble finds the code and data of a program loaded at ProgramAddress
+func Disassemble(program []byte, platform Platform) *Disassembly {
+	d := &Disassembly{
+		Program:    program,
+		Platform:   platform,
+		code:       make([]*Instruction, len(program)),
+		covered:    make([]bool, len(program)),
+		labels:     map[uint16]string{},
+		spriteRows: map[int]int{},
+		exits:      map[int]flowState{},
+	}
+	d.run(flowState{addr: ProgramAddress, sub: ProgramAddress})
+
+	//a table runs until the next code or label
+	for _, table := range d.spriteTables {
+		start := d.offset(int(table.addr))
+		for o := start; o >= 0 && o+table.rowBytes <= len(d.Program) && !d.covered[o]; o += table.rowBytes {
+			if _, ok := d.labels[uint16(o+ProgramAddress)]; ok && o != start {
+				break
+			}
+			d.spriteRows[o] = table.rowBytes
+		}
+	}
+	return d
+}
+
+// DisassembleProgram disassembles a program for the platform it appears to be written for
 func DisassembleProgram(program []byte) string {
-	pc := 0
-	code := ""
+	return Disassemble(program, DetectPlatform(program)).String()
+}
+
+// run follows every path from start
+func (d *Disassembly) run(start flowState) {
+	work := []flowState{start}
+	for len(work) > 0 {
+		state := work[len(work)-1]
+		work = work[:len(work)-1]
+		work = append(work, d.follow(state)...)
+	}
+}
+
+// offset returns the program offset of an address, or -1 if it lies outside the program
+func (d *Disassembly) offset(addr int) int {
+	o := addr - ProgramAddress
+	if o < 0 || o >= len(d.Program) {
+		return -1
+	}
+	return o
+}
+
+// decode marks the instruction at addr as code. It fails if the instruction is invalid, runs
+// off the end of the program, or overlaps another instruction.
+func (d *Disassembly) decode(addr int) (*Instruction, bool) {
+	o := d.offset(addr)
+	if o < 0 {
+		return nil, false
+	}
+	if d.code[o] != nil {
+		return d.code[o], true
+	}
+	inst := DecodeAt(d.Program, o, d.Platform)
+	if inst.Op == OpInvalid || o+inst.Length > len(d.Program) {
+		return nil, false
+	}
+	for i := o; i < o+inst.Length; i++ {
+		if d.covered[i] {
+			return nil, false
+		}
+	}
+	for i := o; i < o+inst.Length; i++ {
+		d.covered[i] = true
+	}
+	d.code[o] = &inst
+	return &inst, true
+}
+
+// follow marks code from state.addr until the flow of control leaves it, returning the other
+// paths still to follow
+func (d *Disassembly) follow(state flowState) []flowState {
+	var branches []flowState
 	for {
-		opCode := uint16(program[pc])<<8 | uint16(program[pc+1])
+		o := d.offset(state.addr)
+		if o >= 0 && d.code[o] != nil {
+			//already followed
+			return branches
+		}
+		inst, ok := d.decode(state.addr)
+		if !ok {
+			return branches
+		}
+		next := state.addr + inst.Length
+
+		switch inst.Op {
+		case OpReturn:
+			if _, ok := d.exits[state.sub]; !ok {
+				d.exits[state.sub] = state
+			}
+			return branches
+		case OpExit:
+			return branches
+		case OpJump:
+			d.label(inst.NNN, "L")
+			state.addr = int(inst.NNN)
+			continue
+		case OpJumpOffset:
+			//usually a jump table; follow its first entry
+			d.label(inst.NNN, "L")
+			state.addr = int(inst.NNN)
+			continue
+		case OpCall:
+			//follow the subroutine first, to learn what it leaves in I. Subroutines often
+			//draw the sprite their caller loaded into I, so it is passed in.
+			d.label(inst.NNN, "sub_")
+			call := state
+			call.addr, call.sub = int(inst.NNN), int(inst.NNN)
+			if !d.IsCode(call.addr) {
+				d.run(call)
+			}
+			if exit, ok := d.exits[call.sub]; ok {
+				state.iState, state.i = exit.iState, exit.i
+			}
+		case OpSkipEqualImm, OpSkipNotEqualImm, OpSkipEqual, OpSkipNotEqual, OpSkipKey, OpSkipNotKey:
+			skipped := WordLength
+			if o := d.offset(next); o >= 0 {
+				skipped = DecodeAt(d.Program, o, d.Platform).Length
+			}
+			skip := state
+			skip.addr = next + skipped
+			branches = append(branches, skip)
+		case OpSetI:
+			state.iState, state.i = iExact, inst.NNN
+			d.label(inst.NNN, "data_")
+		case OpSetILong:
+			state.iState, state.i = iExact, inst.NNNN
+			d.label(inst.NNNN, "data_")
+		case OpAddI, OpStore, OpLoad:
+			if state.iState == iExact {
+				state.iState = iIndexed
+			}
+		case OpFont, OpLargeFont:
+			state.iState = iUnknown
+		case OpDraw:
+			rows, rowBytes := int(inst.N), 1
+			if inst.N == 0 && d.Platform >= PlatformSCHIP {
+				rows, rowBytes = 16, 2
+			}
+			switch state.iState {
+			case iExact:
+				d.markSprite(sprite{addr: state.i, rowBytes: rowBytes, rows: rows})
+			case iIndexed:
+				d.spriteTables = append(d.spriteTables, sprite{addr: state.i, rowBytes: rowBytes})
+			}
+		}
+		state.addr = next
+	}
+}
+
+// label names addr, if it lies in the program, unless it already has a name
+func (d *Disassembly) label(addr uint16, prefix string) {
+	if d.offset(int(addr)) < 0 {
+		return
+	}
+	if _, ok := d.labels[addr]; !ok {
+		d.labels[addr] = fmt.Sprintf("%s%03X", prefix, addr)
+	}
+}
+
+// markSprite records the rows of a sprite
+func (d *Disassembly) markSprite(s sprite) {
+	for row := 0; row < s.rows; row++ {
+		o := d.offset(int(s.addr) + row*s.rowBytes)
+		if o < 0 || o+s.rowBytes > len(d.Program) {
+			return
+		}
+		d.spriteRows[o] = s.rowBytes
+	}
+}
+
+// IsCode reports whether an instruction starts at addr
+func (d *Disassembly) IsCode(addr int) bool {
+	o := d.offset(addr)
+	return o >= 0 && d.code[o] != nil
+}
+
+// Label returns the generated name of addr, if it has one
+func (d *Disassembly) Label(addr uint16) (string, bool) {
+	name, ok := d.labels[addr]
+	if ok && d.labelPlaced(addr) {
+		return name, true
+	}
+	return "", false
+}
+
+// labelPlaced reports whether a label at addr can be written, i.e. addr does not fall inside
+// an instruction
+func (d *Disassembly) labelPlaced(addr uint16) bool {
+	o := d.offset(int(addr))
+	return o >= 0 && (d.code[o] != nil || !d.covered[o])
+}
+
+// address writes an address operand, by name when it has a label
+func (d *Disassembly) address(addr uint16) string {
+	if name, ok := d.Label(addr); ok {
+		return name
+	}
+	return fmt.Sprintf("%#x", addr)
+}
 
-		code += fmt.Sprintf("[%#000x] %s\n", pc+0x200, disassemble(opCode))
-		if pc > len(program)-3 {
-			return code
+// instruction writes an instruction with its address operands replaced by labels
+func (d *Disassembly) instruction(inst *Instruction) string {
+	switch inst.Op {
+	case OpJump, OpCall, OpSetI, OpJumpOffset:
+		return inst.Mnemonic + " " + d.address(inst.NNN)
+	case OpSetILong:
+		return inst.Mnemonic + " " + d.address(inst.NNNN)
+	}
+	return inst.String()
+}
+
+// spriteArt draws sprite bytes as # and . characters
+func spriteArt(row []byte) string {
+	art := ""
+	for _, b := range row {
+		for bit := uint(0); bit < 8; bit++ {
+			if b&(0x80>>bit) != 0 {
+				art += "#"
+			} else {
+				art += "."
+			}
 		}
-		pc += 2
 	}
+	return art
+}
+
+// hexBytes writes bytes as a db operand list
+func hexBytes(data []byte) string {
+	values := make([]string, len(data))
+	for i, b := range data {
+		values[i] = fmt.Sprintf("0x%02X", b)
+	}
+	return strings.Join(values, ", ")
+}
+
+// Syntax is an assembly language a disassembly can be written in
+type Syntax int
+
+const (
+	//the mnemonics of the opcode tables, which the asm package assembles
+	SyntaxNative Syntax = iota
+	//Octo, the most widely used CHIP-8 assembler
+	SyntaxOcto
+)
+
+var syntaxNames = map[string]Syntax{
+	"native": SyntaxNative,
+	"octo":   SyntaxOcto,
 }
 
-func disassemble(opCode uint16) string {
+// LookupSyntax finds a syntax by its name: native or octo
+func LookupSyntax(name string) (Syntax, bool) {
+	s, ok := syntaxNames[name]
+	return s, ok
+}
+
+// String writes the program in the native syntax
+func (d *Disassembly) String() string {
+	return d.Format(SyntaxNative)
+}
+
+// Format writes the program as assembly that assembles back to the same bytes. Each line is
+// commented with its address and, for sprites, a picture of the row.
+func (d *Disassembly) Format(syntax Syntax) string {
+	var out strings.Builder
+	comment, label, data := ";", "%s:\n", "db "
+	if syntax == SyntaxOcto {
+		comment, label, data = "#", ": %s\n", ""
+	}
+	fmt.Fprintf(&out, "%s %d bytes, %s\n", comment, len(d.Program), d.Platform)
+	if syntax == SyntaxOcto {
+		//Octo starts at main, and only leaves out its jump to it when main comes first
+		fmt.Fprintf(&out, label, "main")
+	}
+
+	line := func(text string, note string) {
+		fmt.Fprintf(&out, "\t%-24s %s %s\n", text, comment, note)
+	}
+	byteList := func(b []byte) string {
+		if syntax == SyntaxOcto {
+			return strings.Replace(hexBytes(b), ",", "", -1)
+		}
+		return hexBytes(b)
+	}
 
-	switch opCode & 0xF000 {
-	case 0x0000:
-		switch opCode {
-		case 0x00E0:
-			return "CLS"
-		case 0x00EE:
-			return "RTN"
-		default:
-			return "RCA"
+	for o := 0; o < len(d.Program); {
+		addr := uint16(o + ProgramAddress)
+		if name, ok := d.Label(addr); ok {
+			fmt.Fprintf(&out, label, name)
 		}
-	case 0x1000:
-		//jump to address NNN (0x1NNN)
-		addr := opCode & 0x0FFF
-		return fmt.Sprintf("JMP %#x", addr)
-	case 0x2000:
-		//call subroutine NNN (0x2NNN)
-		addr := opCode & 0x0FFF
-		return fmt.Sprintf("SBR %#x", addr)
-	case 0x3000:
-		// skip next instruction if V[X]==NN (0x3XNN)
-		addr := opCode & 0x00FF
-		x := (opCode & 0x0F00) >> 8
-		return fmt.Sprintf("JEQ v%d,%#x", x, addr)
-	case 0x4000:
-		// skip next instruction if V[X]!=NN (0x4XNN)
-		addr := opCode & 0x00FF
-		x := (opCode & 0x0F00) >> 8
-		return fmt.Sprintf("JNE v%d,%#x", x, addr)
-	case 0x5000:
-		// skip next instruction if V[X]==V[Y] (0x3XY0)
-		x := (opCode & 0x0F00) >> 8
-		y := (opCode & 0x00F0) >> 4
-		return fmt.Sprintf("JEQ v%d,v%d", x, y)
-	case 0x6000:
-		//set V[X] to NN (0x6XNN)addr := opCode & 0x0FFF
-		addr := opCode & 0x00FF
-		x := (opCode & 0x0F00) >> 8
-		return fmt.Sprintf("SET v%d,%#x", x, addr)
-	case 0x7000:
-		//set V[X] to V[X]+NN (0x7XNN)
-		addr := opCode & 0x00FF
-		x := (opCode & 0x0F00) >> 8
-		return fmt.Sprintf("ADD v%d,%#x", x, addr)
-	case 0x8000:
-		switch opCode & 0x000F {
-		case 0x0000:
-			//set V[X] to V[Y] (0x8XY0)
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			return fmt.Sprintf("SET v%d,v%d", x, y)
-		case 0x0001:
-			//set V[X] to V[X] OR V[Y] (0x8XY1)
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			return fmt.Sprintf("OR v%d,v%d", x, y)
-		case 0x0002:
-			//set V[X] to V[X] AND V[Y] (0x8XY2)
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			return fmt.Sprintf("AND v%d,v%d", x, y)
-		case 0x0003:
-			//set V[X] to V[X] XOR V[Y] (0x8XY3)
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			return fmt.Sprintf("XOR v%d,v%d", x, y)
-		case 0x0004:
-			//set V[X] to V[X] + V[Y] (0x8XY4), set V[F] to 1 if carry, otherwise 0
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			return fmt.Sprintf("ADD v%d,v%d", x, y)
-		case 0x0005:
-			//set V[X] to V[X] - V[Y] (0x8XY5), set V[F] to 1 if no borrow, otherwise 0
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			return fmt.Sprintf("SUB v%d,v%d", x, y)
-		case 0x0006:
-			//set V[X] to V[Y] >> 1 (0x8XY6), set V[F] to V[Y] LSB before shift
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			return fmt.Sprintf("BSR v%d,v%d", x, y)
-		case 0x0007:
-			//set V[X] to V[Y] - V[X] (0x8XY7), set V[F] to 1 if no borrow, otherwise 0
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			return fmt.Sprintf("SUB v%d,v%d", y, x)
-		case 0x000E:
-			//set V[X] (and V[Y]) to V[Y] << 1 (0x8XYE), set V[F] to V[Y] MSB before shift
-			x := (opCode & 0x0F00) >> 8
-			y := (opCode & 0x00F0) >> 4
-			return fmt.Sprintf("BSL v%d,v%d", x, y)
+
+		if inst := d.code[o]; inst != nil {
+			text := d.instruction(inst)
+			if syntax == SyntaxOcto {
+				var ok bool
+				if text, ok = d.octoInstruction(inst); !ok {
+					//Octo has no syntax for it, so write its bytes
+					text = byteList(d.Program[o : o+inst.Length])
+				}
+			}
+			line(text, fmt.Sprintf("%03X  %X", addr, d.Program[o:o+inst.Length]))
+			o += inst.Length
+			continue
 		}
-	case 0x9000:
-		//skip next instruction if V[X]!=V[Y] (0x9XY0)
-		x := (opCode & 0x0F00) >> 8
-		y := (opCode & 0x00F0) >> 4
-		return fmt.Sprintf("JNE v%d,v%d", x, y)
-	case 0xA000:
-		//set I to NNN (0xANNN)
-		imm := opCode & 0x0FFF
-		return fmt.Sprintf("ADR %#x", imm)
-	case 0xB000:
-		//jump to addr V[0]+NNN: set PC to V[0] +NNN (0xBNNN)
-		imm := opCode & 0x0FFF
-		return fmt.Sprintf("JMA %#x", imm)
-	case 0xC000:
-		//set V[x] to R+NN where R = random number between 0 and 255(0xCXNN)
-		x := (opCode & 0x0F00) >> 8
-		imm := opCode & 0x00FF
-		return fmt.Sprintf("RND v%d,%#x", x, imm)
-	case 0xD000:
-		//draw sprite at position (V[X],V[Y]) with width 8, heigh N.
-		//sprite bits located at Memory[I] in rows of 8 (0xDXYN)
-		//V[F] is set to 1 if pixels are flipped from 1 to 0, otherwise 0
-		x := (opCode & 0x0F00) >> 8
-		y := (opCode & 0x00F0) >> 4
-		return fmt.Sprintf("DRW v%d,v%d", x, y)
-
-	case 0xE000:
-		switch opCode & 0x00FF {
-		case 0x009E:
-			//skip next instruction if key pressed == v[X] (0xEX9E)
-			x := (opCode & 0x0F00) >> 8
-			return fmt.Sprintf("JKP v%d", x)
-		case 0x00A1:
-			//skip next instruction if key pressed != v[X] (0xEXA1)
-			x := (opCode & 0x0F00) >> 8
-			return fmt.Sprintf("JKN v%d", x)
+
+		//data runs until the next instruction, label or sprite row, and at most 8 bytes
+		end := o + 1
+		if rowBytes, ok := d.spriteRows[o]; ok {
+			end = o + rowBytes
+		} else {
+			for end < len(d.Program) && end-o < 8 && !d.covered[end] && !d.startsData(end) {
+				end++
+			}
 		}
-	case 0xF000:
-		switch opCode & 0x00FF {
-		case 0x0007:
-			//set V[X] to DT (0xFX07)
-			x := (opCode & 0x0F00) >> 8
-			return fmt.Sprintf("SET v%d,DT", x)
-		case 0x000A:
-			//wait for and set v[X] to key pressed (0xFX0A)
-			x := (opCode & 0x0F00) >> 8
-			return fmt.Sprintf("WKP v%d", x)
-		case 0x0015:
-			//set DT to V[X] (0xFX15)
-			x := (opCode & 0x0F00) >> 8
-			return fmt.Sprintf("SET DT,v%d", x)
-		case 0x0018:
-			//set ST to V[X] (0xFX18)
-
-			x := (opCode & 0x0F00) >> 8
-			return fmt.Sprintf("SET ST,v%d", x)
-		case 0x001E:
-			//set I = I + V[X] (0xFX1E)
-		case 0x0029:
-			//set I to sprite address for glyph of V[X] (4x5 px font) (0xFX29)
-			x := (opCode & 0x0F00) >> 8
-			return fmt.Sprintf("Fnt v%d", x)
-		case 0x0033:
-			//get BCD representation of V[X]
-			//Memory[I+0] = Decimal MSB Digit (3)
-			//Memory[I+1] = Decimal Middle Digit (2)
-			//Memory[I+3] = Decimal LSB Digit (3)
-			//(0xFX33)
-			x := (opCode & 0x0F00) >> 8
-			return fmt.Sprintf("BCD v%d", x)
-		case 0x0055:
-			//Store V[0] to V[X] (inclusive) at memory location I, increasing I per register
-			//(0xFX55)
-			return "DMP"
-		case 0x0065:
-			//Set V[0] to V[x] (inclusive) to values from location I, increasing I per register
-			//(0xFX65)
-			return "LOD"
+		for i := o + 1; i < end; i++ {
+			if d.covered[i] || d.startsData(i) {
+				end = i
+				break
+			}
 		}
+
+		note := fmt.Sprintf("%03X", addr)
+		if _, ok := d.spriteRows[o]; ok {
+			note += "  " + spriteArt(d.Program[o:end])
+		}
+		line(data+byteList(d.Program[o:end]), note)
+		o = end
+	}
+	return out.String()
+}
+
+// startsData reports whether a data line must start at offset o, because it is labelled or
+// starts a sprite row
+func (d *Disassembly) startsData(o int) bool {
+	if _, ok := d.spriteRows[o]; ok {
+		return true
 	}
-	return fmt.Sprintf("!!! %#x", opCode)
+	_, ok := d.Label(uint16(o + ProgramAddress))
+	return ok
 }
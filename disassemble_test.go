@@ -0,0 +1,96 @@
+package chip8_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestDisassembleOneByte(t *testing.T) {
+	listing := chip8.DisassembleProgram([]byte{0x12})
+	if !strings.Contains(listing, "db 0x12") {
+		t.Errorf("expected a single data byte:\n%s", listing)
+	}
+}
+
+func TestDisassembleSeparatesData(t *testing.T) {
+	program := []byte{
+		0x12, 0x03, //0x200 - jump over the data byte
+		0xFF,       //0x202 - data
+		0xA2, 0x0B, //0x203 - set I to the sprite
+		0xD0, 0x12, //0x205 - draw it
+		0x22, 0x0D, //0x207 - call
+		0x12, 0x03, //0x209 - loop
+		0x3C,       //0x20B - sprite
+		0x42,       //0x20C
+		0x00, 0xEE, //0x20D - return
+	}
+	d := chip8.Disassemble(program, chip8.PlatformCHIP8)
+
+	for addr, code := range map[int]bool{0x200: true, 0x202: false, 0x203: true, 0x209: true, 0x20B: false, 0x20D: true} {
+		if d.IsCode(addr) != code {
+			t.Errorf("%#x: expected code=%v", addr, code)
+		}
+	}
+
+	listing := d.String()
+	for _, expected := range []string{
+		"JMP L203",
+		"db 0xFF",
+		"L203:\n\tADR data_20B",
+		"DRW v0,v1,0x2",
+		"SBR sub_20D",
+		"data_20B:\n\tdb 0x3C                  ; 20B  ..####..",
+		"db 0x42                  ; 20C  .#....#.",
+		"sub_20D:\n\tRTN",
+	} {
+		if !strings.Contains(listing, expected) {
+			t.Errorf("expected %q in:\n%s", expected, listing)
+		}
+	}
+}
+
+func TestDisassembleSpriteTable(t *testing.T) {
+	program := []byte{
+		0x22, 0x08, //0x200 - call a routine that indexes a table
+		0xD0, 0x12, //0x202 - draw from it
+		0x12, 0x00, //0x204 - loop
+		0xFF, 0x81, //0x206 - table
+		0xA2, 0x06, //0x208 - set I to the table
+		0xF1, 0x1E, //0x20A - index it
+		0x00, 0xEE, //0x20C - return
+	}
+	listing := chip8.Disassemble(program, chip8.PlatformCHIP8).String()
+	if !strings.Contains(listing, "########") || !strings.Contains(listing, "#......#") {
+		t.Errorf("table not drawn as sprites:\n%s", listing)
+	}
+}
+
+func TestDisassembleOcto(t *testing.T) {
+	program := []byte{
+		0x00, 0xE0, //0x200 - clear
+		0x31, 0x05, //0x202 - skip if v1 == 5
+		0x22, 0x0C, //0x204 - call
+		0xA2, 0x0E, //0x206 - set I to the sprite
+		0xD0, 0x15, //0x208 - draw it
+		0x02, 0x34, //0x20A - machine code call
+		0xF3, 0x55, //0x20C - save v0-v3
+		0x3C, //0x20E - sprite
+	}
+	listing := chip8.Disassemble(program, chip8.PlatformCHIP8).Format(chip8.SyntaxOcto)
+	for _, expected := range []string{
+		": main\n\tclear",
+		"if v1 != 0x5 then",
+		":call sub_20C",
+		"i := data_20E",
+		"sprite v0 v1 5",
+		"0x02 0x34",
+		": sub_20C\n\tsave v3",
+		": data_20E\n\t0x3C                     # 20E  ..####..",
+	} {
+		if !strings.Contains(listing, expected) {
+			t.Errorf("expected %q in:\n%s", expected, listing)
+		}
+	}
+}
@@ -1,9 +1,53 @@
 package chip8
 
-const (
-	PIXELS_MONOCHROME = iota
+import (
+	"errors"
+	"image/color"
 )
 
+// ErrNoSDL is returned by NewDisplay and NewSound when the package was built without the sdl tag
+var ErrNoSDL = errors.New("chip8: built without SDL support, rebuild with -tags sdl")
+
+// DefaultScale is the size in screen pixels of a SUPER-CHIP high resolution pixel in a window
+const DefaultScale = 8
+
+// Palette holds the colours of the four XO-CHIP plane combinations: background, plane 1, plane 2 and both
+var Palette = [4]color.RGBA{
+	{R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+	{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	{R: 0xAA, G: 0xAA, B: 0xAA, A: 0xFF},
+	{R: 0x55, G: 0x55, B: 0x55, A: 0xFF},
+}
+
+// Display presents the framebuffer to the user
 type Display interface {
-	Draw(vram []byte, dataType int)
+	//Draw is called once per frame. fb.Dirty() reports whether the image changed since the previous frame
+	Draw(fb *Framebuffer)
+
+	//Closed reports whether the user asked to stop, e.g. by closing the window
+	Closed() bool
+
+	Close()
+}
+
+// Hotkey is an emulator command given from an interactive display
+type Hotkey int
+
+const (
+	//HotkeySaveState saves the machine state (F5 in the SDL window)
+	HotkeySaveState Hotkey = iota + 1
+
+	//HotkeyLoadState restores the last saved state (F9 in the SDL window)
+	HotkeyLoadState
+
+	//HotkeyRewind steps back in time, repeating while held (Backspace in the SDL window)
+	HotkeyRewind
+)
+
+// HotkeyDisplay is a Display that also takes emulator commands from the user
+type HotkeyDisplay interface {
+	Display
+
+	//Hotkeys returns the hotkeys pressed since the last call
+	Hotkeys() []Hotkey
 }
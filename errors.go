@@ -0,0 +1,35 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	//ErrStackOverflow is returned when a subroutine call would nest deeper than the stack
+	ErrStackOverflow = errors.New("chip8: stack overflow")
+
+	//ErrStackUnderflow is returned by a return (00EE) with no subroutine call to return from
+	ErrStackUnderflow = errors.New("chip8: stack underflow")
+
+	//ErrMemoryOutOfBounds is returned when an instruction or load reaches past the end of memory
+	ErrMemoryOutOfBounds = errors.New("chip8: memory access out of bounds")
+)
+
+// ErrInvalidOpcode is returned when the CPU meets an instruction that the platform does not define
+type ErrInvalidOpcode struct {
+	Opcode uint16
+	PC     uint16
+}
+
+func (e ErrInvalidOpcode) Error() string {
+	return fmt.Sprintf("chip8: invalid opcode %04X at %#04x", e.Opcode, e.PC)
+}
+
+// checkMemory returns ErrMemoryOutOfBounds unless the n bytes from addr all lie in memory
+func (c *CPU) checkMemory(addr, n int) error {
+	if addr < 0 || addr+n > len(c.Memory) {
+		return fmt.Errorf("%w: %d bytes at %#04x (PC %#04x)", ErrMemoryOutOfBounds, n, addr, c.PC)
+	}
+	return nil
+}
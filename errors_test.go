@@ -0,0 +1,100 @@
+package chip8_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestStackOverflow(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.LoadData(0x200, []byte{0x22, 0x00}) //0x200 - call self
+
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		err = cpu.Execute()
+	}
+	if !errors.Is(err, chip8.ErrStackOverflow) {
+		t.Fatalf("expected stack overflow, got %v", err)
+	}
+	if int(cpu.SP) != len(cpu.Stack) {
+		t.Errorf("expected full stack, SP is %d", cpu.SP)
+	}
+}
+
+func TestStackUnderflow(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	if err := cpu.ExecuteOp(0x00EE); !errors.Is(err, chip8.ErrStackUnderflow) {
+		t.Fatalf("expected stack underflow, got %v", err)
+	}
+	if cpu.PC != 0x200 {
+		t.Errorf("PC moved to %#x", cpu.PC)
+	}
+}
+
+func TestInvalidOpcode(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	for _, op := range []uint16{0x5121, 0x8128, 0x9121, 0xE1FF, 0xF1FF, 0xF130, 0xF000} {
+		err := cpu.ExecuteOp(op)
+		var invalid chip8.ErrInvalidOpcode
+		if !errors.As(err, &invalid) {
+			t.Errorf("%04X: expected invalid opcode, got %v", op, err)
+			continue
+		}
+		if invalid.Opcode != op || invalid.PC != 0x200 {
+			t.Errorf("%04X: wrong details %+v", op, invalid)
+		}
+	}
+	if cpu.PC != 0x200 {
+		t.Errorf("PC moved to %#x", cpu.PC)
+	}
+}
+
+func TestMemoryOutOfBounds(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.I = 0xFFE
+	for _, op := range []uint16{0xF233, 0xF255, 0xF265, 0xD015} {
+		if err := cpu.ExecuteOp(op); !errors.Is(err, chip8.ErrMemoryOutOfBounds) {
+			t.Errorf("%04X: expected out of bounds, got %v", op, err)
+		}
+	}
+
+	cpu.PC = 0xFFF
+	if err := cpu.Execute(); !errors.Is(err, chip8.ErrMemoryOutOfBounds) {
+		t.Errorf("fetch: expected out of bounds, got %v", err)
+	}
+}
+
+func TestLoadDataBounds(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	if err := cpu.LoadData(0xFFE, []byte{1, 2}); err != nil {
+		t.Errorf("program filling the end of memory: %v", err)
+	}
+	if err := cpu.LoadData(0xFFF, []byte{3, 4}); !errors.Is(err, chip8.ErrMemoryOutOfBounds) {
+		t.Errorf("expected out of bounds, got %v", err)
+	}
+	if cpu.Memory[0xFFF] != 2 {
+		t.Log("partial load written")
+		t.Fail()
+	}
+}
+
+func TestRunReportsFault(t *testing.T) {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	cpu := chip8.NewCPU(ticker.C)
+	cpu.LoadData(0x200, []byte{
+		0x60, 0x01, //0x200 - set v0 to 1
+		0x00, 0xEE, //0x202 - return with nothing on the stack
+	})
+
+	err := cpu.Run(nil)
+	if !errors.Is(err, chip8.ErrStackUnderflow) {
+		t.Fatalf("expected stack underflow, got %v", err)
+	}
+	if cpu.PC != 0x202 {
+		t.Errorf("expected PC at the faulting instruction, got %#x", cpu.PC)
+	}
+}
@@ -0,0 +1,5 @@
+package chip8
+
+// MigrateChunks exposes the upgrading of save states to the tests, which have no older
+// versions of the format to load
+var MigrateChunks = migrateChunks
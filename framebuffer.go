@@ -0,0 +1,285 @@
+package chip8
+
+import "hash/fnv"
+
+const (
+	//ScreenWidth is the width of the chip8 display in pixels
+	ScreenWidth = 64
+
+	//ScreenHeight is the height of the chip8 display in pixels
+	ScreenHeight = 32
+
+	//HiResWidth is the width of the SUPER-CHIP high resolution display in pixels
+	HiResWidth = 128
+
+	//HiResHeight is the height of the SUPER-CHIP high resolution display in pixels
+	HiResHeight = 64
+
+	//PlaneCount is the number of XO-CHIP drawing planes
+	PlaneCount = 2
+)
+
+// Framebuffer is the video memory of the machine. Each pixel holds one bit per XO-CHIP
+// drawing plane, giving four colours; plain CHIP-8 only ever draws to the first plane.
+// Drawing, clearing and scrolling only affect the selected planes.
+type Framebuffer struct {
+	width  int
+	height int
+
+	//one byte per pixel, row-major, bit n set when plane n+1 is lit
+	pixels []byte
+
+	//bitmask of planes affected by drawing (FN01)
+	planes byte
+
+	dirty bool
+}
+
+func NewFramebuffer(width, height int) *Framebuffer {
+	return &Framebuffer{
+		width:  width,
+		height: height,
+		pixels: make([]byte, width*height),
+		planes: 0x01,
+		dirty:  true,
+	}
+}
+
+// Clone returns an independent copy of the framebuffer
+func (f *Framebuffer) Clone() *Framebuffer {
+	clone := *f
+	clone.pixels = append([]byte(nil), f.pixels...)
+	return &clone
+}
+
+// Hash returns an FNV-1a hash of the size and pixels of the framebuffer, to compare frames
+func (f *Framebuffer) Hash() uint32 {
+	h := fnv.New32a()
+	h.Write([]byte{byte(f.width), byte(f.height)})
+	h.Write(f.pixels)
+	return h.Sum32()
+}
+
+func (f *Framebuffer) Width() int {
+	return f.width
+}
+
+func (f *Framebuffer) Height() int {
+	return f.height
+}
+
+func (f *Framebuffer) inBounds(x, y int) bool {
+	return x >= 0 && x < f.width && y >= 0 && y < f.height
+}
+
+// Planes returns the bitmask of planes that drawing affects
+func (f *Framebuffer) Planes() byte {
+	return f.planes
+}
+
+// SelectPlanes sets the bitmask of planes that drawing affects: 1, 2, both (3) or none (0)
+func (f *Framebuffer) SelectPlanes(mask byte) {
+	f.planes = mask & 0x03
+}
+
+// Pixel reports whether the pixel at (x,y) is lit on any plane. Pixels outside the screen are never lit.
+func (f *Framebuffer) Pixel(x, y int) bool {
+	return f.Colour(x, y) != 0
+}
+
+// Colour returns the colour index (0-3) of the pixel at (x,y), one bit per plane
+func (f *Framebuffer) Colour(x, y int) byte {
+	if !f.inBounds(x, y) {
+		return 0
+	}
+	return f.pixels[y*f.width+x]
+}
+
+// SetPixel lights or clears the pixel at (x,y) on the selected planes. Pixels outside the screen are ignored.
+func (f *Framebuffer) SetPixel(x, y int, on bool) {
+	if !f.inBounds(x, y) {
+		return
+	}
+	i := y*f.width + x
+	value := f.pixels[i] &^ f.planes
+	if on {
+		value |= f.planes
+	}
+	if f.pixels[i] != value {
+		f.pixels[i] = value
+		f.dirty = true
+	}
+}
+
+// XORPixel flips the pixel at (x,y) on the selected planes and reports whether a lit pixel was turned off
+func (f *Framebuffer) XORPixel(x, y int) bool {
+	return f.xorPlanes(x, y, f.planes)
+}
+
+func (f *Framebuffer) xorPlanes(x, y int, planes byte) bool {
+	if !f.inBounds(x, y) {
+		return false
+	}
+	i := y*f.width + x
+	collision := f.pixels[i]&planes != 0
+	f.pixels[i] ^= planes
+	f.dirty = true
+	return collision
+}
+
+// Clear turns off every pixel on the selected planes
+func (f *Framebuffer) Clear() {
+	for i := range f.pixels {
+		f.pixels[i] &^= f.planes
+	}
+	f.dirty = true
+}
+
+// Row returns the colours of row y, one byte (0-3) per pixel.
+// The slice aliases the framebuffer and must not be modified.
+func (f *Framebuffer) Row(y int) []byte {
+	if y < 0 || y >= f.height {
+		return nil
+	}
+	return f.pixels[y*f.width : (y+1)*f.width]
+}
+
+// Dirty reports whether the framebuffer changed since the last call to MarkClean
+func (f *Framebuffer) Dirty() bool {
+	return f.dirty
+}
+
+func (f *Framebuffer) MarkClean() {
+	f.dirty = false
+}
+
+// HiRes reports whether the framebuffer is in SUPER-CHIP 128x64 mode
+func (f *Framebuffer) HiRes() bool {
+	return f.width == HiResWidth
+}
+
+// SetHiRes switches between 64x32 and 128x64 pixels, clearing every plane
+func (f *Framebuffer) SetHiRes(hires bool) {
+	f.width, f.height = ScreenWidth, ScreenHeight
+	if hires {
+		f.width, f.height = HiResWidth, HiResHeight
+	}
+	f.pixels = make([]byte, f.width*f.height)
+	f.dirty = true
+}
+
+// scroll moves the selected planes by (dx,dy), clearing what is uncovered
+func (f *Framebuffer) scroll(dx, dy int) {
+	moved := make([]byte, len(f.pixels))
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			if sx, sy := x-dx, y-dy; f.inBounds(sx, sy) {
+				moved[y*f.width+x] = f.pixels[sy*f.width+sx] & f.planes
+			}
+		}
+	}
+	for i := range f.pixels {
+		f.pixels[i] = f.pixels[i]&^f.planes | moved[i]
+	}
+	f.dirty = true
+}
+
+// ScrollDown moves the image down n rows, clearing the rows uncovered at the top
+func (f *Framebuffer) ScrollDown(n int) {
+	f.scroll(0, n)
+}
+
+// ScrollUp moves the image up n rows, clearing the rows uncovered at the bottom
+func (f *Framebuffer) ScrollUp(n int) {
+	f.scroll(0, -n)
+}
+
+// ScrollRight moves the image right n pixels, clearing the columns uncovered on the left
+func (f *Framebuffer) ScrollRight(n int) {
+	f.scroll(n, 0)
+}
+
+// ScrollLeft moves the image left n pixels, clearing the columns uncovered on the right
+func (f *Framebuffer) ScrollLeft(n int) {
+	f.scroll(-n, 0)
+}
+
+// DrawSprite XORs an 8 pixel wide sprite, one byte per row, onto the framebuffer with its
+// top left corner at (x,y). The origin always wraps onto the screen; pixels that run past
+// the edges wrap around when wrap is set and are clipped otherwise.
+// When both planes are selected the first half of sprite is drawn to plane 1 and the
+// second half to plane 2.
+// It reports whether any lit pixel was turned off.
+func (f *Framebuffer) DrawSprite(x, y int, sprite []byte, wrap bool) bool {
+	return f.drawPlanes(x, y, 1, sprite, wrap)
+}
+
+// DrawLargeSprite draws a SUPER-CHIP 16x16 sprite, two bytes per row, like DrawSprite
+func (f *Framebuffer) DrawLargeSprite(x, y int, sprite []byte, wrap bool) bool {
+	return f.drawPlanes(x, y, 2, sprite, wrap)
+}
+
+// SelectedPlaneCount returns the number of planes a sprite is drawn to
+func (f *Framebuffer) SelectedPlaneCount() int {
+	count := 0
+	for plane := byte(0x01); plane < 1<<PlaneCount; plane <<= 1 {
+		if f.planes&plane != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func (f *Framebuffer) drawPlanes(x, y, rowBytes int, sprite []byte, wrap bool) bool {
+	count := f.SelectedPlaneCount()
+	if count == 0 {
+		return false
+	}
+
+	collision := false
+	size := len(sprite) / count
+	i := 0
+	for plane := byte(0x01); plane < 1<<PlaneCount; plane <<= 1 {
+		if f.planes&plane == 0 {
+			continue
+		}
+		if f.drawSprite(x, y, rowBytes, plane, sprite[i*size:(i+1)*size], wrap) {
+			collision = true
+		}
+		i++
+	}
+	return collision
+}
+
+func (f *Framebuffer) drawSprite(x, y, rowBytes int, plane byte, sprite []byte, wrap bool) bool {
+	x %= f.width
+	y %= f.height
+
+	collision := false
+	for row := 0; row*rowBytes < len(sprite); row++ {
+		py := y + row
+		if py >= f.height {
+			if !wrap {
+				break
+			}
+			py %= f.height
+		}
+		for col := 0; col < 8*rowBytes; col++ {
+			i := row*rowBytes + col/8
+			if i >= len(sprite) || sprite[i]&(0x80>>uint(col%8)) == 0 {
+				continue
+			}
+			px := x + col
+			if px >= f.width {
+				if !wrap {
+					break
+				}
+				px %= f.width
+			}
+			if f.xorPlanes(px, py, plane) {
+				collision = true
+			}
+		}
+	}
+	return collision
+}
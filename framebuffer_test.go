@@ -0,0 +1,184 @@
+package chip8_test
+
+import (
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestFramebufferPixels(t *testing.T) {
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+	fb.MarkClean()
+
+	fb.SetPixel(1, 2, true)
+	if !fb.Pixel(1, 2) || !fb.Dirty() {
+		t.Fail()
+	}
+	if fb.Row(2)[1] != 1 {
+		t.Log("row does not reflect pixel")
+		t.Fail()
+	}
+
+	if !fb.XORPixel(1, 2) {
+		t.Log("expected collision when clearing a lit pixel")
+		t.Fail()
+	}
+	if fb.XORPixel(1, 2) {
+		t.Log("unexpected collision when lighting a pixel")
+		t.Fail()
+	}
+
+	//out of bounds access is ignored
+	fb.SetPixel(chip8.ScreenWidth, 0, true)
+	if fb.Pixel(chip8.ScreenWidth, 0) || fb.Pixel(-1, 0) {
+		t.Fail()
+	}
+}
+
+func TestFramebufferIgnoresMemory(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.LoadData(0xF00, []byte{0xFF, 0xFF, 0xFF, 0xFF})
+	for x := 0; x < chip8.ScreenWidth; x++ {
+		if cpu.Framebuffer.Pixel(x, 0) {
+			t.Errorf("pixel (%d,0) set by write to memory", x)
+		}
+	}
+}
+
+func TestDrawSpriteCollision(t *testing.T) {
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+
+	if fb.DrawSprite(0, 0, []byte{0xC0}, false) {
+		t.Log("collision on empty screen")
+		t.Fail()
+	}
+	//overlapping one lit pixel turns it off and collides
+	if !fb.DrawSprite(1, 0, []byte{0xC0}, false) {
+		t.Log("expected collision")
+		t.Fail()
+	}
+	if !fb.Pixel(0, 0) || fb.Pixel(1, 0) || !fb.Pixel(2, 0) {
+		t.Log("pixels not XORed")
+		t.Fail()
+	}
+	//lighting pixels alone never collides
+	if fb.DrawSprite(8, 8, []byte{0xFF, 0xFF}, false) {
+		t.Fail()
+	}
+}
+
+func TestDrawSpriteClipping(t *testing.T) {
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+	fb.DrawSprite(60, 30, []byte{0xFF, 0xFF, 0xFF}, false)
+
+	for x := 60; x < 64; x++ {
+		if !fb.Pixel(x, 30) || !fb.Pixel(x, 31) {
+			t.Errorf("pixel (%d,30) not drawn", x)
+		}
+	}
+	for x := 0; x < 4; x++ {
+		if fb.Pixel(x, 30) || fb.Pixel(x, 0) {
+			t.Errorf("pixel (%d,*) wrapped while clipping", x)
+		}
+	}
+	if fb.Pixel(60, 0) {
+		t.Log("row wrapped while clipping")
+		t.Fail()
+	}
+}
+
+func TestDrawSpriteWrapping(t *testing.T) {
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+	fb.DrawSprite(60, 31, []byte{0xFF, 0x81}, true)
+
+	for x := 60; x < 64; x++ {
+		if !fb.Pixel(x, 31) {
+			t.Errorf("pixel (%d,31) not drawn", x)
+		}
+	}
+	for x := 0; x < 4; x++ {
+		if !fb.Pixel(x, 31) {
+			t.Errorf("pixel (%d,31) not wrapped", x)
+		}
+	}
+	if !fb.Pixel(60, 0) || !fb.Pixel(3, 0) || fb.Pixel(61, 0) {
+		t.Log("second row not wrapped to the top")
+		t.Fail()
+	}
+}
+
+func TestDrawSpriteOriginWraps(t *testing.T) {
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+	fb.DrawSprite(chip8.ScreenWidth+2, chip8.ScreenHeight+1, []byte{0x80}, false)
+	if !fb.Pixel(2, 1) {
+		t.Log("sprite origin not wrapped onto the screen")
+		t.Fail()
+	}
+}
+
+func TestFramebufferHiRes(t *testing.T) {
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+	fb.SetPixel(1, 1, true)
+	fb.SetHiRes(true)
+	if !fb.HiRes() || fb.Width() != chip8.HiResWidth || fb.Height() != chip8.HiResHeight {
+		t.Fatal("not in high resolution")
+	}
+	if fb.Pixel(1, 1) {
+		t.Log("screen not cleared on resolution change")
+		t.Fail()
+	}
+	fb.SetPixel(127, 63, true)
+	if !fb.Pixel(127, 63) {
+		t.Fail()
+	}
+}
+
+func TestFramebufferScroll(t *testing.T) {
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+	fb.SetPixel(10, 0, true)
+
+	fb.ScrollDown(3)
+	if fb.Pixel(10, 0) || !fb.Pixel(10, 3) {
+		t.Log("scroll down")
+		t.Fail()
+	}
+	fb.ScrollRight(4)
+	if fb.Pixel(10, 3) || !fb.Pixel(14, 3) {
+		t.Log("scroll right")
+		t.Fail()
+	}
+	fb.ScrollLeft(4)
+	if fb.Pixel(14, 3) || !fb.Pixel(10, 3) {
+		t.Log("scroll left")
+		t.Fail()
+	}
+	fb.ScrollLeft(11)
+	if fb.Pixel(chip8.ScreenWidth-1, 3) {
+		t.Log("scroll left wrapped")
+		t.Fail()
+	}
+}
+
+func TestDrawLargeSprite(t *testing.T) {
+	fb := chip8.NewFramebuffer(chip8.HiResWidth, chip8.HiResHeight)
+	sprite := make([]byte, 32)
+	sprite[0], sprite[1] = 0x80, 0x01
+	sprite[30], sprite[31] = 0xFF, 0xFF
+
+	if fb.DrawLargeSprite(0, 0, sprite, false) {
+		t.Fail()
+	}
+	if !fb.Pixel(0, 0) || !fb.Pixel(15, 0) || fb.Pixel(1, 0) {
+		t.Log("first row not expected")
+		t.Fail()
+	}
+	for x := 0; x < 16; x++ {
+		if !fb.Pixel(x, 15) {
+			t.Errorf("pixel (%d,15) not drawn", x)
+		}
+	}
+	if !fb.DrawLargeSprite(0, 0, sprite, false) {
+		t.Log("expected collision")
+		t.Fail()
+	}
+}
@@ -1,6 +1,7 @@
 module github.com/alisdairrankine/chip8
 
-require (
-	github.com/alisdairrankine/Chip8 v0.0.0-20180603161800-41f34f5f8f4a // indirect
-	github.com/veandco/go-sdl2 v0.3.0
-)
+go 1.16
+
+require github.com/veandco/go-sdl2 v0.3.0
+
+require github.com/alisdairrankine/Chip8 v0.0.0-20180603161800-41f34f5f8f4a // indirect
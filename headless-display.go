@@ -0,0 +1,76 @@
+package chip8
+
+import "sync"
+
+// HeadlessDisplay is a Display that renders nothing. It keeps copies of the frames it is
+// given so tests and tools can inspect the output without a window system.
+type HeadlessDisplay struct {
+	mu     sync.Mutex
+	keep   int
+	count  int
+	latest *Framebuffer
+	frames []*Framebuffer
+	closed bool
+}
+
+// NewHeadlessDisplay returns a display that records up to keep of the most recent frames in
+// which the image changed
+func NewHeadlessDisplay(keep int) *HeadlessDisplay {
+	return &HeadlessDisplay{
+		keep: keep,
+	}
+}
+
+func (d *HeadlessDisplay) Draw(fb *Framebuffer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.count++
+	if !fb.Dirty() && d.latest != nil {
+		return
+	}
+
+	d.latest = fb.Clone()
+	if d.keep > 0 {
+		if len(d.frames) == d.keep {
+			d.frames = append(d.frames[:0], d.frames[1:]...)
+		}
+		d.frames = append(d.frames, d.latest)
+	}
+}
+
+// Latest returns a copy of the most recently drawn framebuffer, or nil before the first frame
+func (d *HeadlessDisplay) Latest() *Framebuffer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.latest == nil {
+		return nil
+	}
+	return d.latest.Clone()
+}
+
+// Frames returns the recorded frames, oldest first
+func (d *HeadlessDisplay) Frames() []*Framebuffer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*Framebuffer(nil), d.frames...)
+}
+
+// FrameCount returns the number of times Draw has been called
+func (d *HeadlessDisplay) FrameCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.count
+}
+
+func (d *HeadlessDisplay) Closed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closed
+}
+
+func (d *HeadlessDisplay) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+}
@@ -0,0 +1,48 @@
+package chip8_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestHeadlessDisplay(t *testing.T) {
+	clock := make(chan time.Time, 10)
+	for i := 0; i < cap(clock); i++ {
+		clock <- time.Time{}
+	}
+
+	cpu := chip8.NewCPU(clock)
+	cpu.SetPlatform(chip8.PlatformSCHIP)
+	cpu.LoadData(0x200, []byte{
+		0x60, 0x08, //0x200 - set v0 to 8
+		0xF0, 0x29, //0x202 - point I at glyph 8
+		0xD1, 0x15, //0x204 - draw glyph at (v1,v1)
+		0x00, 0xFD, //0x206 - exit
+	})
+
+	display := chip8.NewHeadlessDisplay(4)
+	cpu.Run(display)
+
+	latest := display.Latest()
+	if latest == nil {
+		t.Fatal("no frame recorded")
+	}
+	//glyph 8 is 0xF0, 0x90, 0xF0, 0x90, 0xF0
+	if !latest.Pixel(0, 0) || !latest.Pixel(3, 0) || latest.Pixel(1, 1) || !latest.Pixel(3, 4) {
+		t.Log("latest frame does not show the glyph")
+		t.Fail()
+	}
+	//the draw waits for the next frame (VIP quirks), which then exits without changing the image
+	if display.FrameCount() != 2 || len(display.Frames()) != 1 {
+		t.Errorf("frames drawn: %d, recorded: %d", display.FrameCount(), len(display.Frames()))
+	}
+
+	//the copy is independent of the CPU
+	cpu.Framebuffer.Clear()
+	if !display.Latest().Pixel(0, 0) {
+		t.Log("recorded frame aliases the framebuffer")
+		t.Fail()
+	}
+}
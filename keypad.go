@@ -0,0 +1,121 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// KeyCount is the number of keys on the hex keypad
+const KeyCount = 16
+
+// Keypad is the 16 key hex keypad queried by the CPU
+type Keypad interface {
+	//Pressed reports whether key (0x0-0xF) is held down
+	Pressed(key byte) bool
+
+	//SetPressed updates the state of key. It is safe to call from another goroutine.
+	SetPressed(key byte, pressed bool)
+}
+
+// HexKeypad is a Keypad that is safe for concurrent use
+type HexKeypad struct {
+	mu   sync.RWMutex
+	keys [KeyCount]bool
+}
+
+func NewKeypad() *HexKeypad {
+	return &HexKeypad{}
+}
+
+func (k *HexKeypad) Pressed(key byte) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[key&0x0F]
+}
+
+func (k *HexKeypad) SetPressed(key byte, pressed bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[key&0x0F] = pressed
+}
+
+// KeyMap binds keyboard key names, such as "Q" or "1", to hex keypad keys
+type KeyMap map[string]byte
+
+// DefaultKeyMap returns the conventional layout, mapping the left of a QWERTY keyboard onto
+// the COSMAC VIP keypad:
+//
+//	1 2 3 4      1 2 3 C
+//	Q W E R  ->  4 5 6 D
+//	A S D F      7 8 9 E
+//	Z X C V      A 0 B F
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+		"Q": 0x4, "W": 0x5, "E": 0x6, "R": 0xD,
+		"A": 0x7, "S": 0x8, "D": 0x9, "F": 0xE,
+		"Z": 0xA, "X": 0x0, "C": 0xB, "V": 0xF,
+	}
+}
+
+// Lookup returns the hex key bound to the named keyboard key. Names are case insensitive.
+func (m KeyMap) Lookup(name string) (byte, bool) {
+	key, ok := m[strings.ToUpper(name)]
+	return key, ok
+}
+
+// LoadKeyMap reads key bindings on top of the default layout. Each line holds a keyboard
+// key name followed by the hex key it presses, e.g. "Up 5". Binding a hex key replaces its
+// default binding. Blank lines and lines starting with # are ignored.
+func LoadKeyMap(r io.Reader) (KeyMap, error) {
+	keymap := DefaultKeyMap()
+	rebound := map[byte]bool{}
+	custom := KeyMap{}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("keymap line %d: expected \"<key name> <hex key>\"", line)
+		}
+		key, err := strconv.ParseUint(fields[1], 16, 8)
+		if err != nil || key >= KeyCount {
+			return nil, fmt.Errorf("keymap line %d: invalid hex key %q", line, fields[1])
+		}
+		custom[strings.ToUpper(fields[0])] = byte(key)
+		rebound[byte(key)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for name, key := range keymap {
+		if rebound[key] {
+			delete(keymap, name)
+		}
+	}
+	for name, key := range custom {
+		keymap[name] = key
+	}
+	return keymap, nil
+}
+
+func LoadKeyMapFile(file string) (KeyMap, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadKeyMap(f)
+}
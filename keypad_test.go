@@ -0,0 +1,44 @@
+package chip8_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestLoadKeyMap(t *testing.T) {
+	config := `
+# arrow keys for movement
+Up 5
+left 7
+`
+	keymap, err := chip8.LoadKeyMap(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, ok := keymap.Lookup("up"); !ok || key != 0x5 {
+		t.Log("custom binding not loaded")
+		t.Fail()
+	}
+	if key, ok := keymap.Lookup("Left"); !ok || key != 0x7 {
+		t.Log("custom binding not case insensitive")
+		t.Fail()
+	}
+	if _, ok := keymap.Lookup("W"); ok {
+		t.Log("default binding for rebound key 5 kept")
+		t.Fail()
+	}
+	if key, ok := keymap.Lookup("V"); !ok || key != 0xF {
+		t.Log("default binding lost")
+		t.Fail()
+	}
+}
+
+func TestLoadKeyMapInvalid(t *testing.T) {
+	for _, config := range []string{"Q", "Q 10", "Q Z"} {
+		if _, err := chip8.LoadKeyMap(strings.NewReader(config)); err == nil {
+			t.Errorf("expected error for %q", config)
+		}
+	}
+}
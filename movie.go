@@ -0,0 +1,277 @@
+package chip8
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// movieHeader starts the first line of every movie file, followed by the format version
+const movieHeader = "chip8 movie"
+
+// MovieVersion is the version of the movie format written by Movie.Write
+const MovieVersion = 1
+
+var (
+	//ErrMovieROM is returned when a movie is played with a different ROM from the one recorded
+	ErrMovieROM = errors.New("chip8: movie was recorded with a different ROM")
+
+	//ErrMovieStart is returned when recording starts after the machine has begun running
+	ErrMovieStart = errors.New("chip8: movies must be recorded from power on with a SeededRandom")
+)
+
+// DesyncError is returned when playing a movie back does not draw the frames it recorded
+type DesyncError struct {
+	//Frame is the first frame whose framebuffer differed
+	Frame int
+
+	//Final reports whether the last frame matched nonetheless
+	Final bool
+}
+
+func (e *DesyncError) Error() string {
+	final := "differs"
+	if e.Final {
+		final = "matches"
+	}
+	return fmt.Sprintf("chip8: movie desynced at frame %d, final frame %s", e.Frame, final)
+}
+
+// Movie is a recorded session: everything needed to run a ROM again exactly as it ran, with
+// the frames it drew to check the replay against
+type Movie struct {
+	ROM      [sha1.Size]byte
+	Platform Platform
+	Quirks   Quirks
+	IPS      int
+	Seed     uint64
+	Frames   []MovieFrame
+}
+
+// MovieFrame is the input to and output of a frame
+type MovieFrame struct {
+	//Keys has bit n set while key n was held
+	Keys uint16
+
+	//Framebuffer is the Framebuffer.Hash at the end of the frame
+	Framebuffer uint32
+}
+
+// quirkNames are the names of the quirks in movie files
+var quirkNames = []struct {
+	name string
+	flag func(q *Quirks) *bool
+}{
+	{"ShiftVX", func(q *Quirks) *bool { return &q.ShiftVX }},
+	{"IncrementI", func(q *Quirks) *bool { return &q.IncrementI }},
+	{"IncrementIByX", func(q *Quirks) *bool { return &q.IncrementIByX }},
+	{"JumpVX", func(q *Quirks) *bool { return &q.JumpVX }},
+	{"WrapSprites", func(q *Quirks) *bool { return &q.WrapSprites }},
+	{"ResetVF", func(q *Quirks) *bool { return &q.ResetVF }},
+	{"DisplayWait", func(q *Quirks) *bool { return &q.DisplayWait }},
+}
+
+// RecordMovie starts a movie of c running rom. c must have loaded rom and not yet run, and
+// use a SeededRandom. Frames are recorded by drawing through the returned display, which
+// wraps display.
+func RecordMovie(c *CPU, rom []byte, display Display) (*Movie, Display, error) {
+	random, ok := c.Random.(*SeededRandom)
+	if !ok || c.Cycles != 0 || c.Frames != 0 {
+		return nil, nil, ErrMovieStart
+	}
+	m := &Movie{
+		ROM:      sha1.Sum(rom),
+		Platform: c.Platform,
+		Quirks:   c.Quirks,
+		IPS:      c.IPS,
+		Seed:     random.Seed(),
+	}
+	return m, &movieRecorder{Display: display, movie: m, keypad: c.Keypad, keys: keypadMask(c.Keypad)}, nil
+}
+
+// movieRecorder records a frame each time it is drawn. Interactive displays deliver key
+// presses while drawing, so the keys read after drawing are those of the next frame.
+type movieRecorder struct {
+	Display
+	movie  *Movie
+	keypad Keypad
+	keys   uint16
+}
+
+func (r *movieRecorder) Draw(fb *Framebuffer) {
+	r.movie.Frames = append(r.movie.Frames, MovieFrame{Keys: r.keys, Framebuffer: fb.Hash()})
+	r.Display.Draw(fb)
+	r.keys = keypadMask(r.keypad)
+}
+
+// Play runs rom on c as the movie recorded it, as fast as possible. c is set up with the
+// movie's platform, quirks, speed and seed, and must not have loaded a program. Play returns
+// a *DesyncError if any frame differs from the recording, and stops early if the program
+// faults before the recording ended.
+func (m *Movie) Play(c *CPU, rom []byte) error {
+	if sha1.Sum(rom) != m.ROM {
+		return ErrMovieROM
+	}
+	c.SetPlatform(m.Platform)
+	c.Quirks = m.Quirks
+	c.IPS = m.IPS
+	c.Random = NewRandom(m.Seed)
+	if err := c.LoadProgram(rom); err != nil {
+		return err
+	}
+
+	var desync *DesyncError
+	for n, frame := range m.Frames {
+		setKeypadMask(c.Keypad, frame.Keys)
+		fault := c.Frame()
+		matches := c.Framebuffer.Hash() == frame.Framebuffer
+		if !matches && desync == nil {
+			desync = &DesyncError{Frame: n}
+		}
+		if desync != nil && n == len(m.Frames)-1 {
+			desync.Final = matches
+		}
+		if fault != nil {
+			//a recording ends with the fault that stopped it, which is replayed too
+			if desync != nil {
+				return desync
+			}
+			return fault
+		}
+	}
+	if desync != nil {
+		return desync
+	}
+	return nil
+}
+
+// Write writes the movie as text: a header of "name value" lines, then a line per frame of
+// the keys and framebuffer hash in hex
+func (m *Movie) Write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "%s %d\n", movieHeader, MovieVersion)
+	fmt.Fprintf(out, "rom %x\n", m.ROM)
+	for name, p := range platformNames {
+		if p == m.Platform {
+			fmt.Fprintf(out, "platform %s\n", name)
+		}
+	}
+	var quirks []string
+	for _, quirk := range quirkNames {
+		if *quirk.flag(&m.Quirks) {
+			quirks = append(quirks, quirk.name)
+		}
+	}
+	fmt.Fprintf(out, "quirks %s\n", strings.Join(quirks, " "))
+	fmt.Fprintf(out, "ips %d\n", m.IPS)
+	fmt.Fprintf(out, "seed %d\n", m.Seed)
+	fmt.Fprintf(out, "frames %d\n", len(m.Frames))
+	for _, frame := range m.Frames {
+		fmt.Fprintf(out, "%04x %08x\n", frame.Keys, frame.Framebuffer)
+	}
+	return out.Flush()
+}
+
+// ReadMovie reads a movie written by Movie.Write
+func ReadMovie(r io.Reader) (*Movie, error) {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	next := func() (string, []string, bool) {
+		if !scanner.Scan() {
+			return "", nil, false
+		}
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			return "", nil, true
+		}
+		return fields[0], fields[1:], true
+	}
+	bad := func(format string, args ...interface{}) error {
+		return fmt.Errorf("chip8: movie line %d: %s", line, fmt.Sprintf(format, args...))
+	}
+
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), movieHeader+" ") {
+		return nil, errors.New("chip8: not a movie file")
+	}
+	line++
+	version, err := strconv.Atoi(strings.TrimPrefix(scanner.Text(), movieHeader+" "))
+	if err != nil || version < 1 || version > MovieVersion {
+		return nil, bad("unsupported version %q", strings.TrimPrefix(scanner.Text(), movieHeader+" "))
+	}
+
+	m := &Movie{}
+	frames := -1
+	for frames < 0 {
+		name, values, ok := next()
+		if !ok {
+			return nil, bad("missing frames")
+		}
+		value := strings.Join(values, " ")
+		switch name {
+		case "":
+			continue
+		case "rom":
+			hash, err := hex.DecodeString(value)
+			if err != nil || len(hash) != len(m.ROM) {
+				return nil, bad("bad ROM hash %q", value)
+			}
+			copy(m.ROM[:], hash)
+		case "platform":
+			if m.Platform, ok = LookupPlatform(value); !ok {
+				return nil, bad("unknown platform %q", value)
+			}
+		case "quirks":
+		quirks:
+			for _, v := range values {
+				for _, quirk := range quirkNames {
+					if quirk.name == v {
+						*quirk.flag(&m.Quirks) = true
+						continue quirks
+					}
+				}
+				return nil, bad("unknown quirk %q", v)
+			}
+		case "ips":
+			if m.IPS, err = strconv.Atoi(value); err != nil {
+				return nil, bad("bad ips %q", value)
+			}
+		case "seed":
+			if m.Seed, err = strconv.ParseUint(value, 10, 64); err != nil {
+				return nil, bad("bad seed %q", value)
+			}
+		case "frames":
+			if frames, err = strconv.Atoi(value); err != nil || frames < 0 {
+				return nil, bad("bad frame count %q", value)
+			}
+		default:
+			return nil, bad("unknown field %q", name)
+		}
+	}
+
+	//the count is not trusted for an allocation, as the file may not have that many frames
+	for len(m.Frames) < frames {
+		keys, values, ok := next()
+		if !ok {
+			return nil, bad("movie ends after %d of %d frames", len(m.Frames), frames)
+		}
+		k, err := strconv.ParseUint(keys, 16, 16)
+		if err != nil || len(values) != 1 {
+			return nil, bad("bad frame")
+		}
+		hash, err := strconv.ParseUint(values[0], 16, 32)
+		if err != nil {
+			return nil, bad("bad frame")
+		}
+		m.Frames = append(m.Frames, MovieFrame{Keys: uint16(k), Framebuffer: uint32(hash)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,104 @@
+package chip8_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+	"github.com/alisdairrankine/chip8/asm"
+)
+
+// keyScript is a display that presses keys between frames, as an interactive display does
+type keyScript struct {
+	*chip8.HeadlessDisplay
+	keypad chip8.Keypad
+	frame  int
+}
+
+func (k *keyScript) Draw(fb *chip8.Framebuffer) {
+	k.HeadlessDisplay.Draw(fb)
+	k.frame++
+	k.keypad.SetPressed(0x4, k.frame%7 < 3)
+	k.keypad.SetPressed(0x6, k.frame%11 < 5)
+}
+
+// a dot steered by keys 4 and 6, leaving a random trail
+const steer = `
+loop:
+	SET v2,4
+	JKN v2
+	ADD v0,-1
+	SET v2,6
+	JKN v2
+	ADD v0,1
+	ADR dot
+	RND v3,1
+	ADD v1,v3
+	DRW v0,v1,1
+	JMP loop
+dot:
+	db 0x80
+`
+
+func recordSteer(t *testing.T, frames int) ([]byte, *chip8.Movie) {
+	rom, err := asm.Assemble("steer.asm", []byte(steer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := chip8.NewCPU(nil)
+	cpu.Random = chip8.NewRandom(99)
+	cpu.LoadProgram(rom)
+	movie, display, err := chip8.RecordMovie(cpu, rom, &keyScript{HeadlessDisplay: chip8.NewHeadlessDisplay(0), keypad: cpu.Keypad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < frames; i++ {
+		cpu.Frame()
+		display.Draw(cpu.Framebuffer)
+	}
+	return rom, movie
+}
+
+func TestMoviePlayback(t *testing.T) {
+	rom, movie := recordSteer(t, 100)
+
+	var file bytes.Buffer
+	if err := movie.Write(&file); err != nil {
+		t.Fatal(err)
+	}
+	read, err := chip8.ReadMovie(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Frames) != 100 || read.Seed != 99 || read.Quirks != chip8.VIP {
+		t.Errorf("movie changed by writing and reading it: %d frames, seed %d", len(read.Frames), read.Seed)
+	}
+
+	if err := read.Play(chip8.NewCPU(nil), rom); err != nil {
+		t.Errorf("playback: %v", err)
+	}
+}
+
+func TestMovieDesync(t *testing.T) {
+	rom, movie := recordSteer(t, 100)
+	movie.Frames[40].Keys ^= 1 << 0x4
+
+	err := movie.Play(chip8.NewCPU(nil), rom)
+	var desync *chip8.DesyncError
+	if !errors.As(err, &desync) || desync.Frame < 40 || desync.Final {
+		t.Errorf("expected a desync from frame 40, got %v", err)
+	}
+
+	if err := movie.Play(chip8.NewCPU(nil), append(rom, 0)); err != chip8.ErrMovieROM {
+		t.Errorf("expected ErrMovieROM, got %v", err)
+	}
+}
+
+func TestReadMovieTruncated(t *testing.T) {
+	file := "chip8 movie 1\nplatform chip8\nips 700\nseed 1\nframes 99999999999\n0000 00000000\n"
+	if _, err := chip8.ReadMovie(strings.NewReader(file)); err == nil || !strings.Contains(err.Error(), "after 1 of 99999999999 frames") {
+		t.Errorf("expected a truncated movie error, got %v", err)
+	}
+}
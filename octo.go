@@ -0,0 +1,121 @@
+package chip8
+
+import "fmt"
+
+// octoInstruction writes an instruction in Octo syntax, with its address operands replaced by
+// labels. It fails for instructions Octo cannot write, which must be written as bytes.
+func (d *Disassembly) octoInstruction(inst *Instruction) (string, bool) {
+	vx, vy := fmt.Sprintf("v%x", inst.X), fmt.Sprintf("v%x", inst.Y)
+	nn := fmt.Sprintf("%#x", inst.NN)
+
+	//Octo's "if ... then" runs the next instruction when the condition holds, so it is the
+	//opposite of the skip it compiles to
+	switch inst.Op {
+	case OpClear:
+		return "clear", true
+	case OpReturn:
+		return "return", true
+	case OpScrollDown:
+		return fmt.Sprintf("scroll-down %d", inst.N), true
+	case OpScrollUp:
+		return fmt.Sprintf("scroll-up %d", inst.N), true
+	case OpScrollRight:
+		return "scroll-right", true
+	case OpScrollLeft:
+		return "scroll-left", true
+	case OpExit:
+		return "exit", true
+	case OpLowRes:
+		return "lores", true
+	case OpHighRes:
+		return "hires", true
+	case OpJump:
+		return "jump " + d.address(inst.NNN), true
+	case OpCall:
+		return ":call " + d.address(inst.NNN), true
+	case OpSkipEqualImm:
+		return "if " + vx + " != " + nn + " then", true
+	case OpSkipNotEqualImm:
+		return "if " + vx + " == " + nn + " then", true
+	case OpSkipEqual:
+		return "if " + vx + " != " + vy + " then", true
+	case OpSkipNotEqual:
+		return "if " + vx + " == " + vy + " then", true
+	case OpSaveRange:
+		return "save " + vx + " - " + vy, true
+	case OpLoadRange:
+		return "load " + vx + " - " + vy, true
+	case OpSetImm:
+		return vx + " := " + nn, true
+	case OpAddImm:
+		return vx + " += " + nn, true
+	case OpSet:
+		return vx + " := " + vy, true
+	case OpOr:
+		return vx + " |= " + vy, true
+	case OpAnd:
+		return vx + " &= " + vy, true
+	case OpXor:
+		return vx + " ^= " + vy, true
+	case OpAdd:
+		return vx + " += " + vy, true
+	case OpSub:
+		return vx + " -= " + vy, true
+	case OpShiftRight:
+		return vx + " >>= " + vy, true
+	case OpSubReverse:
+		return vx + " =- " + vy, true
+	case OpShiftLeft:
+		return vx + " <<= " + vy, true
+	case OpSetI:
+		return "i := " + d.address(inst.NNN), true
+	case OpJumpOffset:
+		return "jump0 " + d.address(inst.NNN), true
+	case OpRandom:
+		return vx + " := random " + nn, true
+	case OpDraw:
+		return fmt.Sprintf("sprite %s %s %d", vx, vy, inst.N), true
+	case OpSkipKey:
+		return "if " + vx + " -key then", true
+	case OpSkipNotKey:
+		return "if " + vx + " key then", true
+	case OpSetILong:
+		return "i := long " + d.address(inst.NNNN), true
+	case OpPlanes:
+		//Octo only selects the planes that exist
+		if inst.X > 3 {
+			return "", false
+		}
+		return fmt.Sprintf("plane %d", inst.X), true
+	case OpAudio:
+		return "audio", true
+	case OpGetDelay:
+		return vx + " := delay", true
+	case OpWaitKey:
+		return vx + " := key", true
+	case OpSetDelay:
+		return "delay := " + vx, true
+	case OpSetSound:
+		return "buzzer := " + vx, true
+	case OpAddI:
+		return "i += " + vx, true
+	case OpFont:
+		return "i := hex " + vx, true
+	case OpLargeFont:
+		return "i := bighex " + vx, true
+	case OpBCD:
+		return "bcd " + vx, true
+	case OpPitch:
+		return "pitch := " + vx, true
+	case OpStore:
+		return "save " + vx, true
+	case OpLoad:
+		return "load " + vx, true
+	case OpSaveFlags:
+		return "saveflags " + vx, true
+	case OpLoadFlags:
+		return "loadflags " + vx, true
+	}
+	//machine code calls and invalid opcodes
+	return "", false
+}
@@ -0,0 +1,76 @@
+package chip8
+
+// Platform is the instruction set a program is written for
+type Platform int
+
+const (
+	//PlatformCHIP8 is the original COSMAC VIP instruction set
+	PlatformCHIP8 Platform = iota
+
+	//PlatformSCHIP adds the SUPER-CHIP 1.1 scrolling, high resolution and large font opcodes
+	PlatformSCHIP
+
+	//PlatformXOCHIP adds Octo's XO-CHIP 64KiB memory, drawing planes and audio to SUPER-CHIP
+	PlatformXOCHIP
+)
+
+func (p Platform) String() string {
+	switch p {
+	case PlatformCHIP8:
+		return "CHIP-8"
+	case PlatformSCHIP:
+		return "SUPER-CHIP"
+	case PlatformXOCHIP:
+		return "XO-CHIP"
+	}
+	return "unknown"
+}
+
+var platformNames = map[string]Platform{
+	"chip8":  PlatformCHIP8,
+	"schip":  PlatformSCHIP,
+	"xochip": PlatformXOCHIP,
+}
+
+// MemorySize is the number of bytes of addressable memory
+func (p Platform) MemorySize() int {
+	if p >= PlatformXOCHIP {
+		return 0x10000
+	}
+	return 0x1000
+}
+
+// LookupPlatform returns the named platform, e.g. "chip8" or "xochip"
+func LookupPlatform(name string) (Platform, bool) {
+	p, ok := platformNames[name]
+	return p, ok
+}
+
+// DetectPlatform guesses the platform a ROM was written for from the opcodes it contains.
+// Every aligned word is treated as an instruction, so sprite data can cause a false positive.
+func DetectPlatform(rom []byte) Platform {
+	if len(rom) > PlatformCHIP8.MemorySize()-ProgramAddress {
+		return PlatformXOCHIP
+	}
+	detected := PlatformCHIP8
+	for i := 0; i+1 < len(rom); i += WordLength {
+		if p := opcodePlatform(uint16(rom[i])<<8 | uint16(rom[i+1])); p > detected {
+			detected = p
+		}
+	}
+	return detected
+}
+
+// opcodePlatform returns the first platform to define an opcode
+func opcodePlatform(opCode uint16) Platform {
+	inst := Decode(opCode, PlatformXOCHIP)
+	switch {
+	case inst.Op == OpDraw && inst.N == 0:
+		//DXY0 draws nothing before SUPER-CHIP's 16x16 sprites
+		return PlatformSCHIP
+	case (inst.Op == OpScrollDown || inst.Op == OpScrollUp) && inst.N == 0:
+		//scrolling by nothing is more likely to be data
+		return PlatformCHIP8
+	}
+	return inst.Op.Info().Platform
+}
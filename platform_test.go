@@ -0,0 +1,24 @@
+package chip8_test
+
+import (
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	tests := []struct {
+		rom      []byte
+		expected chip8.Platform
+	}{
+		{[]byte{0x60, 0x01, 0xD0, 0x15, 0x12, 0x00}, chip8.PlatformCHIP8},
+		{[]byte{0x00, 0xFF, 0xD0, 0x10, 0x12, 0x00}, chip8.PlatformSCHIP},
+		{[]byte{0x00, 0xFF, 0xF0, 0x00, 0x30, 0x00, 0xF2, 0x01}, chip8.PlatformXOCHIP},
+		{make([]byte, 0x1000), chip8.PlatformXOCHIP},
+	}
+	for i, test := range tests {
+		if p := chip8.DetectPlatform(test.rom); p != test.expected {
+			t.Errorf("%d: expected %s, got %s", i, test.expected, p)
+		}
+	}
+}
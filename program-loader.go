@@ -1,11 +1,146 @@
 package chip8
 
-import "os"
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
 
-func LoadProgram(file string) []byte {
-	mem := []byte{}
+// ProgramAddress is where programs are loaded and start executing
+const ProgramAddress = 0x200
 
-	f, _ := os.Open(file)
-	f.Read(mem)
-	return mem
+var (
+	//ErrEmptyROM is returned when a ROM contains no program
+	ErrEmptyROM = errors.New("chip8: empty ROM")
+
+	//ErrROMTooLarge is returned when a ROM does not fit in memory after ProgramAddress
+	ErrROMTooLarge = errors.New("chip8: ROM too large")
+
+	//ErrDisassemblyListing is returned for disassembler output, which cannot be turned back
+	//into the program it came from
+	ErrDisassemblyListing = errors.New("chip8: file is a disassembly listing, not a ROM")
+)
+
+// LoadROM reads a program from r. Both raw binary ROMs and hex text dumps are accepted;
+// a hex dump is whitespace or comma separated bytes or words, with optional 0x prefixes,
+// "addr:" labels and #, ; or // comments. A file is only read as a hex dump if that is all it
+// contains and it parses; anything else, even if it is printable text, is a binary ROM.
+// The ROM is checked against the largest memory of any platform; CPU.LoadProgram checks
+// that it fits the platform in use.
+func LoadROM(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if isText(data) && bytes.HasPrefix(bytes.TrimSpace(data), []byte("[0x")) {
+		return nil, ErrDisassemblyListing
+	}
+	if isHexDump(data) {
+		if rom, err := parseHexDump(data); err == nil && len(rom) > 0 {
+			data = rom
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, ErrEmptyROM
+	}
+	if room := PlatformXOCHIP.MemorySize() - ProgramAddress; len(data) > room {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d fit in memory", ErrROMTooLarge, len(data), room)
+	}
+	return data, nil
+}
+
+// LoadROMFile reads a program from a file, like LoadROM
+func LoadROMFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rom, err := LoadROM(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rom, nil
+}
+
+// LoadProgram copies rom into memory at ProgramAddress, failing with ErrROMTooLarge
+// if it does not fit the platform's memory
+func (c *CPU) LoadProgram(rom []byte) error {
+	if room := len(c.Memory) - ProgramAddress; len(rom) > room {
+		return fmt.Errorf("%w: %d bytes, %s has room for %d", ErrROMTooLarge, len(rom), c.Platform, room)
+	}
+	return c.LoadData(ProgramAddress, rom)
+}
+
+// isText reports whether data is entirely printable ASCII and whitespace
+func isText(data []byte) bool {
+	for _, b := range data {
+		if (b < 0x20 || b > 0x7E) && b != '\n' && b != '\r' && b != '\t' {
+			return false
+		}
+	}
+	return len(data) > 0
+}
+
+// isHexDump reports whether data is text made only of what a hex dump contains: hex digits,
+// 0x prefixes, separators, "addr:" labels and comments
+func isHexDump(data []byte) bool {
+	if !isText(data) {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		for _, r := range stripComment(line) {
+			if !strings.ContainsRune("0123456789abcdefABCDEFxX:, \t\r", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stripComment removes a #, ; or // comment from a line of a hex dump
+func stripComment(line string) string {
+	for _, comment := range []string{"#", ";", "//"} {
+		if i := strings.Index(line, comment); i >= 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
+
+// parseHexDump decodes a hex text dump, failing if any token is not hex
+func parseHexDump(data []byte) ([]byte, error) {
+	var rom []byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := stripComment(scanner.Text())
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		for _, field := range fields {
+			if strings.HasSuffix(field, ":") {
+				//address label
+				continue
+			}
+			field = strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X")
+			if len(field) == 0 || len(field)%2 != 0 {
+				return nil, fmt.Errorf("line %d: bad hex value %q", n, field)
+			}
+			for i := 0; i < len(field); i += 2 {
+				b, err := strconv.ParseUint(field[i:i+2], 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: bad hex value %q", n, field)
+				}
+				rom = append(rom, byte(b))
+			}
+		}
+	}
+	return rom, scanner.Err()
 }
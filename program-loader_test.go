@@ -0,0 +1,101 @@
+package chip8_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestLoadROMBinary(t *testing.T) {
+	rom, err := chip8.LoadROMFile("ROMs/TETRIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rom) != 494 || rom[0] != 0xA2 || rom[1] != 0xB4 {
+		t.Errorf("unexpected ROM: %d bytes starting % X", len(rom), rom[:2])
+	}
+}
+
+func TestLoadROMHexDump(t *testing.T) {
+	expected := []byte{0xA2, 0xB4, 0x23, 0xE6, 0x22, 0xB6}
+	for _, dump := range []string{
+		"A2 B4 23 E6 22 B6\n",
+		"a2b4 23e6\n22b6",
+		"0xA2, 0xB4, 0x23, 0xE6, 0x22, 0xB6,",
+		"# tetris\n0200: A2B4 23E6 ; set I\n0204: 22B6 // call\n",
+	} {
+		rom, err := chip8.LoadROM(strings.NewReader(dump))
+		if err != nil {
+			t.Errorf("%q: %v", dump, err)
+			continue
+		}
+		if !bytes.Equal(rom, expected) {
+			t.Errorf("%q: got % X", dump, rom)
+		}
+	}
+}
+
+func TestLoadROMTetrisDump(t *testing.T) {
+	dump, err := chip8.LoadROMFile("programs/tetris.c8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rom, err := chip8.LoadROMFile("ROMs/TETRIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dump, rom) {
+		t.Error("programs/tetris.c8 is not the same program as ROMs/TETRIS")
+	}
+}
+
+func TestLoadROMPrintableBinary(t *testing.T) {
+	//SET v0,0x41; SET v0,0x42; ADD v0,0x51 happens to be printable
+	for _, binary := range []string{"`A`BpQ", "`A`B\npQ\n"} {
+		rom, err := chip8.LoadROM(strings.NewReader(binary))
+		if err != nil || string(rom) != binary {
+			t.Errorf("%q: got % X, %v", binary, rom, err)
+		}
+	}
+	//hex digits that do not pair up are not a dump either
+	if rom, err := chip8.LoadROM(strings.NewReader("A2B")); err != nil || string(rom) != "A2B" {
+		t.Errorf("odd hex: got % X, %v", rom, err)
+	}
+}
+
+func TestLoadROMRejects(t *testing.T) {
+	if _, err := chip8.LoadROM(strings.NewReader("[0x200] ADR 0x2b4\n[0x202] SBR 0x3e6\n")); !errors.Is(err, chip8.ErrDisassemblyListing) {
+		t.Errorf("listing: %v", err)
+	}
+	if _, err := chip8.LoadROMFile("programs/1.c8"); !errors.Is(err, chip8.ErrEmptyROM) {
+		t.Errorf("empty: %v", err)
+	}
+	if _, err := chip8.LoadROMFile("programs/missing.c8"); err == nil {
+		t.Error("missing file loaded")
+	}
+	huge := make([]byte, chip8.PlatformXOCHIP.MemorySize())
+	if _, err := chip8.LoadROM(bytes.NewReader(huge)); !errors.Is(err, chip8.ErrROMTooLarge) {
+		t.Errorf("huge: %v", err)
+	}
+}
+
+func TestLoadProgramPlatformSize(t *testing.T) {
+	rom := make([]byte, 0x1000)
+	rom[0] = 0x12
+
+	cpu := chip8.NewCPU(nil)
+	if err := cpu.LoadProgram(rom); !errors.Is(err, chip8.ErrROMTooLarge) {
+		t.Errorf("expected 4K ROM not to fit CHIP-8 memory, got %v", err)
+	}
+
+	cpu.SetPlatform(chip8.PlatformXOCHIP)
+	if err := cpu.LoadProgram(rom); err != nil {
+		t.Fatal(err)
+	}
+	if cpu.Memory[chip8.ProgramAddress] != 0x12 {
+		t.Error("program not loaded at ProgramAddress")
+	}
+}
@@ -0,0 +1,89 @@
+package chip8
+
+import "sort"
+
+// Quirks selects between the behaviours of historical interpreters for ambiguous opcodes
+type Quirks struct {
+	//8XY6 and 8XYE shift VX in place instead of shifting VY into VX
+	ShiftVX bool
+
+	//FX55 and FX65 leave I pointing past the last register transferred
+	IncrementI bool
+
+	//FX55 and FX65 leave I at I+X, one short of IncrementI, as CHIP-48 did
+	IncrementIByX bool
+
+	//BNNN jumps to XNN+V[X] instead of NNN+V[0]
+	JumpVX bool
+
+	//sprites wrap around the screen edges instead of being clipped
+	WrapSprites bool
+
+	//8XY1, 8XY2 and 8XY3 reset V[F] to 0
+	ResetVF bool
+
+	//DXYN waits for the vertical blank, so at most one sprite is drawn per frame
+	DisplayWait bool
+}
+
+var (
+	//VIP is the original COSMAC VIP interpreter
+	VIP = Quirks{
+		IncrementI:  true,
+		ResetVF:     true,
+		DisplayWait: true,
+	}
+
+	//CHIP48 is the HP-48 interpreter that most 1990s games were written against
+	CHIP48 = Quirks{
+		ShiftVX:       true,
+		IncrementIByX: true,
+		JumpVX:        true,
+	}
+
+	//SCHIP11 is SUPER-CHIP 1.1 on the HP-48, which stopped FX55 and FX65 moving I
+	SCHIP11 = Quirks{
+		ShiftVX: true,
+		JumpVX:  true,
+	}
+
+	//XOCHIP is Octo's XO-CHIP
+	XOCHIP = Quirks{
+		IncrementI:  true,
+		WrapSprites: true,
+	}
+)
+
+var quirksProfiles = map[string]Quirks{
+	"vip":     VIP,
+	"chip48":  CHIP48,
+	"schip11": SCHIP11,
+	"xochip":  XOCHIP,
+}
+
+// LookupQuirks returns the named quirks profile, e.g. "vip" or "schip11"
+func LookupQuirks(name string) (Quirks, bool) {
+	q, ok := quirksProfiles[name]
+	return q, ok
+}
+
+// QuirksProfiles lists the names accepted by LookupQuirks
+func QuirksProfiles() []string {
+	names := make([]string, 0, len(quirksProfiles))
+	for name := range quirksProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PlatformQuirks returns the quirks of the interpreter that defined a platform
+func PlatformQuirks(p Platform) Quirks {
+	switch p {
+	case PlatformSCHIP:
+		return SCHIP11
+	case PlatformXOCHIP:
+		return XOCHIP
+	}
+	return VIP
+}
@@ -0,0 +1,57 @@
+package chip8
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// RandomSource supplies the random numbers of CXNN. Sources that also implement
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler are kept in save states, so that a
+// restored machine draws the same numbers.
+type RandomSource interface {
+	Byte() byte
+}
+
+// SeededRandom is a deterministic RandomSource: the same seed always gives the same numbers
+type SeededRandom struct {
+	seed  uint64
+	state uint64
+}
+
+// NewRandom returns a SeededRandom starting from seed
+func NewRandom(seed uint64) *SeededRandom {
+	return &SeededRandom{seed: seed, state: seed}
+}
+
+// Seed returns the seed the source started from
+func (r *SeededRandom) Seed() uint64 {
+	return r.seed
+}
+
+// Byte returns the next number, using SplitMix64
+func (r *SeededRandom) Byte() byte {
+	r.state += 0x9E3779B97F4A7C15
+	z := r.state
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	z ^= z >> 31
+	return byte(z >> 56)
+}
+
+// MarshalBinary writes the seed and position of the source
+func (r *SeededRandom) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data, r.seed)
+	binary.BigEndian.PutUint64(data[8:], r.state)
+	return data, nil
+}
+
+// UnmarshalBinary restores a source written by MarshalBinary
+func (r *SeededRandom) UnmarshalBinary(data []byte) error {
+	if len(data) != 16 {
+		return errors.New("chip8: bad random source state")
+	}
+	r.seed = binary.BigEndian.Uint64(data)
+	r.state = binary.BigEndian.Uint64(data[8:])
+	return nil
+}
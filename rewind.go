@@ -0,0 +1,222 @@
+package chip8
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io/ioutil"
+)
+
+const (
+	//DefaultRewindInterval is the number of frames between rewind snapshots
+	DefaultRewindInterval = 30
+
+	//DefaultRewindLimit is the number of bytes of compressed snapshots a rewind buffer keeps
+	DefaultRewindLimit = 16 << 20
+)
+
+// ErrNoHistory is returned when stepping back past the oldest rewind snapshot
+var ErrNoHistory = errors.New("chip8: no rewind history that far back")
+
+// Rewind records a CPU's history so that it can step backwards. It keeps a compressed save
+// state every Interval frames, dropping the oldest once they take more than Limit bytes, and
+// the keypad state of every frame since the oldest. Going back restores the nearest snapshot
+// and replays from it.
+type Rewind struct {
+	cpu *CPU
+
+	Interval int
+	Limit    int
+
+	//oldest first
+	snapshots []snapshot
+	size      int
+
+	//keypad state at the first instruction of each frame from keysFrom
+	keys     []uint16
+	keysFrom uint64
+}
+
+// snapshot is a compressed save state taken at the start of a frame
+type snapshot struct {
+	frames uint64
+	cycles uint64
+	state  []byte
+}
+
+// NewRewind starts recording the history of c, from its current state
+func NewRewind(c *CPU, interval, limit int) *Rewind {
+	if interval < 1 {
+		interval = DefaultRewindInterval
+	}
+	r := &Rewind{cpu: c, Interval: interval, Limit: limit}
+	c.Rewind = r
+	r.Reset()
+	return r
+}
+
+// Reset forgets the history, as after loading a save state, and starts again from now
+func (r *Rewind) Reset() {
+	r.snapshots, r.size = nil, 0
+	r.keys, r.keysFrom = nil, r.cpu.Frames
+	r.snapshot()
+}
+
+// Oldest returns the frame and cycle counts of the furthest point that can be stepped back to
+func (r *Rewind) Oldest() (frames, cycles uint64) {
+	return r.snapshots[0].frames, r.snapshots[0].cycles
+}
+
+// snapshot saves the current state
+func (r *Rewind) snapshot() {
+	var compressed bytes.Buffer
+	w, _ := flate.NewWriter(&compressed, flate.BestSpeed)
+	if err := r.cpu.SaveState(w); err != nil || w.Close() != nil {
+		return
+	}
+	s := snapshot{frames: r.cpu.Frames, cycles: r.cpu.Cycles, state: compressed.Bytes()}
+	r.snapshots = append(r.snapshots, s)
+	r.size += len(s.state)
+
+	for len(r.snapshots) > 1 && r.size > r.Limit {
+		r.size -= len(r.snapshots[0].state)
+		r.snapshots = r.snapshots[1:]
+	}
+	if oldest := r.snapshots[0].frames; oldest > r.keysFrom {
+		r.keys = r.keys[oldest-r.keysFrom:]
+		r.keysFrom = oldest
+	}
+}
+
+// endFrame is called by the CPU at the start of each frame
+func (r *Rewind) endFrame() {
+	last := r.snapshots[len(r.snapshots)-1]
+	if r.cpu.Frames >= last.frames+uint64(r.Interval) {
+		r.snapshot()
+	}
+}
+
+// logKeys is called by the CPU before the first instruction of each frame, once the display
+// has delivered the frame's key presses
+func (r *Rewind) logKeys() {
+	frame := r.cpu.Frames
+	if frame < r.keysFrom {
+		return
+	}
+	mask := keypadMask(r.cpu.Keypad)
+	//frames without instructions keep the keys they started with
+	for r.keysFrom+uint64(len(r.keys)) <= frame {
+		r.keys = append(r.keys, mask)
+	}
+	r.keys[frame-r.keysFrom] = mask
+}
+
+// StepBack undoes the last instruction
+func (r *Rewind) StepBack() error {
+	if r.cpu.Cycles == 0 {
+		return ErrNoHistory
+	}
+	target := r.cpu.Cycles - 1
+	return r.goBack(func(s snapshot) bool { return s.cycles <= target }, func(c *CPU) bool { return c.Cycles == target })
+}
+
+// StepBackFrames goes back n frames, or as far as the history goes
+func (r *Rewind) StepBackFrames(n int) error {
+	target := uint64(0)
+	if r.cpu.Frames > uint64(n) {
+		target = r.cpu.Frames - uint64(n)
+	}
+	if oldest, _ := r.Oldest(); target < oldest {
+		target = oldest
+	}
+	return r.goBack(func(s snapshot) bool { return s.frames <= target }, func(c *CPU) bool { return c.Frames == target })
+}
+
+// goBack restores the latest snapshot that is not past the target, replays until reached
+// reports the target has been reached, and forgets the history after it
+func (r *Rewind) goBack(before func(snapshot) bool, reached func(*CPU) bool) error {
+	n := len(r.snapshots) - 1
+	for n >= 0 && !before(r.snapshots[n]) {
+		n--
+	}
+	if n < 0 {
+		return ErrNoHistory
+	}
+
+	c := r.cpu
+	keys := keypadMask(c.Keypad)
+	beeping := c.beeping
+	tracer, sound := c.Tracer, c.Sound
+	c.Tracer, c.Sound, c.Rewind = nil, nil, nil
+	defer func() {
+		c.Tracer, c.Sound, c.Rewind = tracer, sound, r
+		//part way through a frame the keys stay as logged for it, as they were when it ran
+		if c.frameCycle == 0 {
+			setKeypadMask(c.Keypad, keys)
+		}
+		//let Sound catch up with where the replay left the sound timer
+		c.beeping = beeping
+		c.updateSound()
+	}()
+
+	state, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(r.snapshots[n].state)))
+	if err != nil {
+		return err
+	}
+	if err := c.loadState(bytes.NewReader(state), true); err != nil {
+		return err
+	}
+
+	//replay as Frame does, with the logged keys
+	for !reached(c) {
+		if c.frameCycle >= c.frameBudget() {
+			c.endFrame()
+			continue
+		}
+		if c.frameCycle == 0 && c.Frames >= r.keysFrom && c.Frames-r.keysFrom < uint64(len(r.keys)) {
+			setKeypadMask(c.Keypad, r.keys[c.Frames-r.keysFrom])
+		}
+		if err := c.Step(); err != nil {
+			return err
+		}
+	}
+
+	r.snapshots = r.snapshots[:n+1]
+	r.size = 0
+	for _, s := range r.snapshots {
+		r.size += len(s.state)
+	}
+	//keep the keys of the current frame if it has started
+	kept := c.Frames - r.keysFrom
+	if c.frameCycle > 0 {
+		kept++
+	}
+	if kept < uint64(len(r.keys)) {
+		r.keys = r.keys[:kept]
+	}
+	return nil
+}
+
+// keypadMask returns the pressed keys of k, one bit per key
+func keypadMask(k Keypad) uint16 {
+	var mask uint16
+	if k == nil {
+		return mask
+	}
+	for key := byte(0); key < KeyCount; key++ {
+		if k.Pressed(key) {
+			mask |= 1 << key
+		}
+	}
+	return mask
+}
+
+// setKeypadMask presses the keys of mask on k and releases the rest
+func setKeypadMask(k Keypad, mask uint16) {
+	if k == nil {
+		return
+	}
+	for key := byte(0); key < KeyCount; key++ {
+		k.SetPressed(key, mask&(1<<key) != 0)
+	}
+}
@@ -0,0 +1,74 @@
+package chip8_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+	"github.com/alisdairrankine/chip8/asm"
+)
+
+func TestStepBack(t *testing.T) {
+	cpu := newWalker(t)
+	chip8.NewRewind(cpu, 4, chip8.DefaultRewindLimit)
+	frames(t, cpu, 9)
+
+	var history [][]byte
+	for i := 0; i < 40; i++ {
+		history = append(history, saveState(t, cpu))
+		if err := cpu.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if err := cpu.Rewind.StepBack(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(saveState(t, cpu), history[i]) {
+			t.Fatalf("stepping back to cycle %d did not restore it", cpu.Cycles)
+		}
+	}
+}
+
+func TestStepBackReplaysKeys(t *testing.T) {
+	//counts the instructions run while key 5 is up
+	program, err := asm.Assemble("keys.asm", []byte("SET v0,5\nloop: JKN v0\nADD v1,1\nJMP loop\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := chip8.NewCPU(nil)
+	cpu.LoadProgram(program)
+	chip8.NewRewind(cpu, 100, chip8.DefaultRewindLimit)
+
+	for frame := 0; frame < 20; frame++ {
+		cpu.Keypad.SetPressed(0x5, frame >= 7 && frame < 12)
+		frames(t, cpu, 1)
+	}
+	before := saveState(t, cpu)
+	cpu.Step()
+	if err := cpu.Rewind.StepBack(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saveState(t, cpu), before) {
+		t.Error("replay did not press the keys that were pressed")
+	}
+}
+
+func TestRewindLimit(t *testing.T) {
+	cpu := newWalker(t)
+	rewind := chip8.NewRewind(cpu, 2, 1)
+	frames(t, cpu, 10)
+	cycles := cpu.Cycles
+
+	if frames, _ := rewind.Oldest(); frames != 10 {
+		t.Errorf("kept history from frame %d with no room for it", frames)
+	}
+	cpu.Step()
+	cpu.Step()
+	if err := rewind.StepBackFrames(100); err != nil || cpu.Frames != 10 || cpu.Cycles != cycles {
+		t.Errorf("rewound to frame %d cycle %d: %v", cpu.Frames, cpu.Cycles, err)
+	}
+	if err := rewind.StepBack(); err != chip8.ErrNoHistory {
+		t.Errorf("expected ErrNoHistory, got %v", err)
+	}
+}
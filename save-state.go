@@ -0,0 +1,287 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+// StateVersion is the version of the save state format written by SaveState
+const StateVersion = 1
+
+// stateMagic starts every save state
+const stateMagic = "CH8S"
+
+var (
+	//ErrStateCorrupt is returned by LoadState for data that is not a save state, or that fails its checksum
+	ErrStateCorrupt = errors.New("chip8: save state is corrupt")
+
+	//ErrStateVersion is returned by LoadState for a save state written by a newer version
+	ErrStateVersion = errors.New("chip8: save state is from a newer version")
+)
+
+// A save state is stateMagic, the format version as a big-endian uint16, a series of chunks
+// and a CRC-32 of everything before it. Each chunk is a 4 byte ID, a big-endian uint32 length
+// and that many bytes of data. Unknown chunks are ignored.
+const (
+	chunkCPU         = "CPU "
+	chunkMemory      = "MEM "
+	chunkFramebuffer = "FB  "
+	chunkKeypad      = "KEYS"
+	chunkQuirks      = "QRKS"
+	chunkRandom      = "RNG "
+)
+
+// stateMigrations upgrade the chunks of a save state written by an older version of the
+// format, by the version they upgrade from. Each one returns chunks of the next version.
+var stateMigrations = map[uint16]func(chunks map[string][]byte) error{}
+
+// cpuState is the CPU chunk
+type cpuState struct {
+	V            [16]byte
+	I            uint16
+	PC           uint16
+	Stack        [48]uint16
+	SP           byte
+	DT           byte
+	ST           byte
+	Platform     byte
+	RPL          [16]byte
+	AudioPattern [16]byte
+	Pitch        byte
+	WaitVBlank   bool
+	KeyWaiting   bool
+	KeyWait      byte
+	Finished     bool
+	IPS          uint32
+	Frames       uint64
+	Cycles       uint64
+	FrameCycle   uint32
+}
+
+// framebufferState is the framebuffer chunk, followed by one byte per pixel
+type framebufferState struct {
+	Width  uint16
+	Height uint16
+	Planes byte
+}
+
+// SaveState writes the state of the machine: registers, timers, memory, framebuffer, keypad,
+// quirks and, if it can be marshalled, the random source. The clock, tracer and sound devices
+// are not part of it.
+func (c *CPU) SaveState(w io.Writer) error {
+	var out bytes.Buffer
+	out.WriteString(stateMagic)
+	binary.Write(&out, binary.BigEndian, uint16(StateVersion))
+
+	chunk := func(id string, values ...interface{}) {
+		var data bytes.Buffer
+		for _, v := range values {
+			binary.Write(&data, binary.BigEndian, v)
+		}
+		out.WriteString(id)
+		binary.Write(&out, binary.BigEndian, uint32(data.Len()))
+		out.Write(data.Bytes())
+	}
+
+	chunk(chunkCPU, cpuState{
+		V:            c.V,
+		I:            c.I,
+		PC:           c.PC,
+		Stack:        c.Stack,
+		SP:           c.SP,
+		DT:           c.DT,
+		ST:           c.ST,
+		Platform:     byte(c.Platform),
+		RPL:          c.RPL,
+		AudioPattern: c.AudioPattern,
+		Pitch:        c.Pitch,
+		WaitVBlank:   c.waitVBlank,
+		KeyWaiting:   c.keyWaiting,
+		KeyWait:      c.keyWait,
+		Finished:     c.Finished,
+		IPS:          uint32(c.IPS),
+		Frames:       c.Frames,
+		Cycles:       c.Cycles,
+		FrameCycle:   uint32(c.frameCycle),
+	})
+	chunk(chunkMemory, c.Memory)
+	fb := c.Framebuffer
+	chunk(chunkFramebuffer, framebufferState{uint16(fb.width), uint16(fb.height), fb.planes}, fb.pixels)
+	var keys [KeyCount]bool
+	if c.Keypad != nil {
+		for key := range keys {
+			keys[key] = c.Keypad.Pressed(byte(key))
+		}
+	}
+	chunk(chunkKeypad, keys)
+	chunk(chunkQuirks, c.Quirks)
+	if random, ok := c.Random.(encoding.BinaryMarshaler); ok {
+		data, err := random.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		chunk(chunkRandom, data)
+	}
+
+	binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(out.Bytes()))
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// LoadState restores a state written by SaveState, upgrading states from older versions. The
+// CPU is only changed if the whole state is valid. The keypad is left as the user is holding
+// it, rather than pressing the keys that were held when the state was saved.
+func (c *CPU) LoadState(r io.Reader) error {
+	return c.loadState(r, false)
+}
+
+// loadState restores a state, pressing the saved keys too when restoreKeys is set, as a replay
+// of the frames that followed needs
+func (c *CPU) loadState(r io.Reader, restoreKeys bool) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	chunks, err := readChunks(data)
+	if err != nil {
+		return err
+	}
+
+	var state cpuState
+	if err := decodeChunk(chunks, chunkCPU, &state); err != nil {
+		return err
+	}
+	platform := Platform(state.Platform)
+	if platform < PlatformCHIP8 || platform > PlatformXOCHIP || int(state.SP) > len(state.Stack) {
+		return fmt.Errorf("%w: bad CPU state", ErrStateCorrupt)
+	}
+	memory := chunks[chunkMemory]
+	if len(memory) != platform.MemorySize() {
+		return fmt.Errorf("%w: %d bytes of memory for %s", ErrStateCorrupt, len(memory), platform)
+	}
+
+	var fbState framebufferState
+	fbData := chunks[chunkFramebuffer]
+	if err := decodeChunk(chunks, chunkFramebuffer, &fbState); err != nil {
+		return err
+	}
+	fb := NewFramebuffer(ScreenWidth, ScreenHeight)
+	fb.SetHiRes(fbState.Width == HiResWidth)
+	fb.SelectPlanes(fbState.Planes)
+	header := binary.Size(fbState)
+	if int(fbState.Width) != fb.width || int(fbState.Height) != fb.height || len(fbData)-header != len(fb.pixels) {
+		return fmt.Errorf("%w: bad framebuffer", ErrStateCorrupt)
+	}
+	copy(fb.pixels, fbData[header:])
+
+	var keys [KeyCount]bool
+	if err := decodeChunk(chunks, chunkKeypad, &keys); err != nil {
+		return err
+	}
+	var quirks Quirks
+	if err := decodeChunk(chunks, chunkQuirks, &quirks); err != nil {
+		return err
+	}
+
+	//checked last, as a custom source can only be restored in place
+	if data, ok := chunks[chunkRandom]; ok {
+		if c.Random == nil {
+			c.Random = NewRandom(0)
+		}
+		if random, ok := c.Random.(encoding.BinaryUnmarshaler); ok {
+			if err := random.UnmarshalBinary(data); err != nil {
+				return fmt.Errorf("%w: %s", ErrStateCorrupt, err)
+			}
+		}
+	}
+
+	c.V, c.I, c.PC = state.V, state.I, state.PC
+	c.Stack, c.SP = state.Stack, state.SP
+	c.DT, c.ST = state.DT, state.ST
+	c.Platform = platform
+	c.RPL, c.AudioPattern, c.Pitch = state.RPL, state.AudioPattern, state.Pitch
+	c.waitVBlank = state.WaitVBlank
+	c.keyWaiting, c.keyWait = state.KeyWaiting, state.KeyWait
+	c.Finished = state.Finished
+	c.IPS = int(state.IPS)
+	c.Frames, c.Cycles, c.frameCycle = state.Frames, state.Cycles, int(state.FrameCycle)
+	c.Memory = append([]byte(nil), memory...)
+	c.Framebuffer = fb
+	if restoreKeys && c.Keypad != nil {
+		for key, pressed := range keys {
+			c.Keypad.SetPressed(byte(key), pressed)
+		}
+	}
+	c.Quirks = quirks
+
+	if c.Platform >= PlatformXOCHIP {
+		c.updatePattern()
+	}
+	c.updateSound()
+	return nil
+}
+
+// readChunks checks the framing and checksum of a save state, returning its chunks upgraded
+// to the current version
+func readChunks(data []byte) (map[string][]byte, error) {
+	const header, trailer = len(stateMagic) + 2, 4
+	if len(data) < header+trailer || string(data[:len(stateMagic)]) != stateMagic {
+		return nil, fmt.Errorf("%w: not a save state", ErrStateCorrupt)
+	}
+	body := data[:len(data)-trailer]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(data[len(body):]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrStateCorrupt)
+	}
+	version := binary.BigEndian.Uint16(data[len(stateMagic):])
+	if version > StateVersion {
+		return nil, fmt.Errorf("%w: version %d, up to %d is supported", ErrStateVersion, version, StateVersion)
+	}
+
+	chunks := map[string][]byte{}
+	for rest := body[header:]; len(rest) > 0; {
+		if len(rest) < 8 {
+			return nil, fmt.Errorf("%w: truncated chunk", ErrStateCorrupt)
+		}
+		id, size := string(rest[:4]), binary.BigEndian.Uint32(rest[4:8])
+		rest = rest[8:]
+		if uint32(len(rest)) < size {
+			return nil, fmt.Errorf("%w: truncated %q chunk", ErrStateCorrupt, id)
+		}
+		chunks[id], rest = rest[:size], rest[size:]
+	}
+
+	if err := migrateChunks(chunks, version, StateVersion, stateMigrations); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// migrateChunks upgrades the chunks of a save state from version to current, one version at a
+// time
+func migrateChunks(chunks map[string][]byte, version, current uint16, migrations map[uint16]func(chunks map[string][]byte) error) error {
+	for ; version < current; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return fmt.Errorf("%w: version %d cannot be upgraded", ErrStateVersion, version)
+		}
+		if err := migrate(chunks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeChunk reads the fixed size value at the start of a chunk
+func decodeChunk(chunks map[string][]byte, id string, v interface{}) error {
+	data, ok := chunks[id]
+	if !ok || len(data) < binary.Size(v) {
+		return fmt.Errorf("%w: missing %q chunk", ErrStateCorrupt, id)
+	}
+	return binary.Read(bytes.NewReader(data), binary.BigEndian, v)
+}
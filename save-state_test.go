@@ -0,0 +1,149 @@
+package chip8_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+	"github.com/alisdairrankine/chip8/asm"
+)
+
+// moves a sprite across the screen, beeping, counting in memory and rolling dice
+const walker = `
+	ADR sprite
+loop:
+	DRW v0,v1,3
+	ADD v0,1
+	ADD v1,2
+	SBR count
+	SET ST,v2
+	JMP loop
+count:
+	ADD v2,1
+	RND v3,0xFF
+	ADR counter
+	DMP v2
+	ADR sprite
+	RTN
+sprite:
+	db 0xE0, 0xA0, 0xE0
+counter:
+	db 0, 0, 0
+`
+
+func newWalker(t *testing.T) *chip8.CPU {
+	program, err := asm.Assemble("walker.asm", []byte(walker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := chip8.NewCPU(nil)
+	cpu.Random = chip8.NewRandom(1)
+	if err := cpu.LoadProgram(program); err != nil {
+		t.Fatal(err)
+	}
+	return cpu
+}
+
+func frames(t *testing.T, cpu *chip8.CPU, n int) {
+	for i := 0; i < n; i++ {
+		if err := cpu.Frame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func saveState(t *testing.T, cpu *chip8.CPU) []byte {
+	var state bytes.Buffer
+	if err := cpu.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+	return state.Bytes()
+}
+
+func TestSaveStateRestores(t *testing.T) {
+	cpu := newWalker(t)
+	frames(t, cpu, 10)
+	cpu.Keypad.SetPressed(0x5, true)
+	saved := saveState(t, cpu)
+
+	frames(t, cpu, 20)
+	expected := saveState(t, cpu)
+
+	restored := newWalker(t)
+	restored.Random = chip8.NewRandom(2)
+	restored.Framebuffer.SetHiRes(true)
+	restored.SetPlatform(chip8.PlatformXOCHIP)
+	restored.Quirks = chip8.XOCHIP
+	if err := restored.LoadState(bytes.NewReader(saved)); err != nil {
+		t.Fatal(err)
+	}
+	//the keys are the user's, not the state's
+	if restored.Keypad.Pressed(0x5) || restored.Frames != 10 {
+		t.Errorf("state not restored: key 5 %v, %d frames", restored.Keypad.Pressed(0x5), restored.Frames)
+	}
+	restored.Keypad.SetPressed(0x5, true)
+	frames(t, restored, 20)
+	if !bytes.Equal(saveState(t, restored), expected) {
+		t.Error("restored machine ran differently")
+	}
+}
+
+func TestLoadStateRejectsBadData(t *testing.T) {
+	cpu := newWalker(t)
+	frames(t, cpu, 3)
+	saved := saveState(t, cpu)
+
+	corrupt := append([]byte(nil), saved...)
+	corrupt[len(corrupt)/2] ^= 0x01
+	newer := append([]byte(nil), saved...)
+	newer[5] = chip8.StateVersion + 1
+	binary.BigEndian.PutUint32(newer[len(newer)-4:], crc32.ChecksumIEEE(newer[:len(newer)-4]))
+
+	for name, test := range map[string]struct {
+		data     []byte
+		expected error
+	}{
+		"corrupt":   {corrupt, chip8.ErrStateCorrupt},
+		"truncated": {saved[:len(saved)-1], chip8.ErrStateCorrupt},
+		"empty":     {nil, chip8.ErrStateCorrupt},
+		"newer":     {newer, chip8.ErrStateVersion},
+	} {
+		fresh := newWalker(t)
+		err := fresh.LoadState(bytes.NewReader(test.data))
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, err)
+		}
+		if fresh.Frames != 0 {
+			t.Errorf("%s: CPU changed by a failed load", name)
+		}
+	}
+}
+
+func TestMigrateChunks(t *testing.T) {
+	migrations := map[uint16]func(chunks map[string][]byte) error{
+		1: func(chunks map[string][]byte) error {
+			chunks["NEW "] = []byte{1}
+			return nil
+		},
+		2: func(chunks map[string][]byte) error {
+			chunks["NEW "] = append(chunks["NEW "], 2)
+			delete(chunks, "OLD ")
+			return nil
+		},
+	}
+	chunks := map[string][]byte{"OLD ": {0}}
+	if err := chip8.MigrateChunks(chunks, 1, 3, migrations); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := chunks["OLD "]; ok || !bytes.Equal(chunks["NEW "], []byte{1, 2}) {
+		t.Errorf("migrated to %v", chunks)
+	}
+
+	//a version with no migration cannot be upgraded
+	if err := chip8.MigrateChunks(map[string][]byte{}, 0, 3, migrations); !errors.Is(err, chip8.ErrStateVersion) {
+		t.Errorf("version 0 migrated with %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package chip8
+
+const (
+	//TimerFrequency is the rate in Hz at which DT and ST count down and the display refreshes
+	TimerFrequency = 60
+
+	//DefaultIPS is the default number of instructions executed per second
+	DefaultIPS = 700
+)
+
+// Run executes one frame per tick of the CPU clock and draws the framebuffer after each one,
+// until the program finishes or the display is closed. If the program faults Run stops and
+// returns the error, leaving the CPU at the faulting instruction. With a Debugger, breakpoints
+// and faults pause instead, and the display keeps being drawn while the debugger is paused.
+// Without a Clock Run returns at once.
+func (c *CPU) Run(display Display) error {
+	if c.Clock == nil {
+		return nil
+	}
+	for {
+		select {
+		case <-c.Clock:
+			var err error
+			if c.Debugger == nil || !c.Debugger.Paused() {
+				err = c.Frame()
+			}
+			if c.Debugger != nil {
+				if err != nil {
+					//pause at faults as well as breakpoints, to look at what went wrong
+					c.Debugger.stopped(err)
+					err = nil
+				}
+				c.Debugger.Poll()
+				if c.Debugger.Quit() {
+					return nil
+				}
+			}
+			if display != nil {
+				display.Draw(c.Framebuffer)
+				c.Framebuffer.MarkClean()
+				if hotkeys, ok := display.(HotkeyDisplay); ok {
+					for _, hotkey := range hotkeys.Hotkeys() {
+						if c.OnHotkey != nil {
+							c.OnHotkey(hotkey)
+						}
+					}
+				}
+				if display.Closed() {
+					return nil
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if c.Finished {
+				return nil
+			}
+		}
+	}
+}
+
+// Frame runs the rest of the current frame: instructions until the frame's share of IPS has
+// been executed, followed by a timer tick. It stops early if an instruction fails, or with
+// ErrBreakpoint before an instruction the Debugger stops at.
+func (c *CPU) Frame() error {
+	frame := c.Frames
+	for c.Frames == frame && !c.Finished {
+		if c.frameCycle >= c.frameBudget() {
+			//below 60 IPS some frames run no instructions at all
+			c.endFrame()
+			return nil
+		}
+		if c.Debugger != nil && c.Debugger.stopBefore() {
+			return ErrBreakpoint
+		}
+		if err := c.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Step executes a single instruction, ending the frame once its instruction budget is spent
+// or, with Quirks.DisplayWait, once a sprite has been drawn
+func (c *CPU) Step() error {
+	if c.Rewind != nil && c.frameCycle == 0 {
+		c.Rewind.logKeys()
+	}
+	if err := c.Execute(); err != nil {
+		return err
+	}
+	c.Cycles++
+	c.frameCycle++
+	if c.frameCycle >= c.frameBudget() || c.waitVBlank {
+		c.endFrame()
+	}
+	return nil
+}
+
+// frameBudget is the number of instructions in the current frame. Frames are given
+// alternately floor and ceil of IPS/60 so that exactly IPS instructions run every second.
+func (c *CPU) frameBudget() int {
+	ips := uint64(c.IPS)
+	if c.IPS <= 0 {
+		ips = DefaultIPS
+	}
+	frame := c.Frames % TimerFrequency
+	return int(ips*(frame+1)/TimerFrequency - ips*frame/TimerFrequency)
+}
+
+func (c *CPU) endFrame() {
+	c.Frames++
+	c.TickTimers()
+	c.frameCycle = 0
+	c.waitVBlank = false
+	if c.Rewind != nil {
+		c.Rewind.endFrame()
+	}
+}
+
+// TickTimers counts DT and ST down by one, as happens every 1/60th of a second
+func (c *CPU) TickTimers() {
+	if c.DT > 0 {
+		c.DT -= 1
+	}
+	if c.ST > 0 {
+		c.ST -= 1
+	}
+	c.updateSound()
+}
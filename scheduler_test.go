@@ -0,0 +1,84 @@
+package chip8_test
+
+import (
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestFrameInstructionBudget(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.IPS = 700
+	cpu.LoadData(0x200, []byte{0x12, 0x00}) //0x200 - jump to self
+
+	cpu.Frame()
+	if cpu.Cycles != 11 || cpu.Frames != 1 {
+		t.Errorf("first frame: %d cycles, %d frames", cpu.Cycles, cpu.Frames)
+	}
+	for i := 1; i < chip8.TimerFrequency; i++ {
+		cpu.Frame()
+	}
+	if cpu.Cycles != 700 || cpu.Frames != chip8.TimerFrequency {
+		t.Errorf("one second: %d cycles, %d frames", cpu.Cycles, cpu.Frames)
+	}
+}
+
+func TestTimersTickOncePerFrame(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.IPS = 1000
+	cpu.LoadData(0x200, []byte{0x12, 0x00})
+	cpu.DT = 10
+	cpu.ST = 1
+
+	cpu.Step()
+	if cpu.DT != 10 || cpu.ST != 1 {
+		t.Log("timers decremented by an instruction")
+		t.Fail()
+	}
+	cpu.Frame()
+	if cpu.DT != 9 || cpu.ST != 0 {
+		t.Errorf("DT: %d, ST: %d after one frame", cpu.DT, cpu.ST)
+	}
+	for i := 0; i < 20; i++ {
+		cpu.Frame()
+	}
+	if cpu.DT != 0 || cpu.ST != 0 {
+		t.Log("timers went below zero")
+		t.Fail()
+	}
+}
+
+func TestFrameBelowTimerFrequency(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	cpu.IPS = 30
+	cpu.LoadData(0x200, []byte{0x12, 0x00})
+	for i := 0; i < chip8.TimerFrequency; i++ {
+		cpu.Frame()
+	}
+	if cpu.Cycles != 30 {
+		t.Errorf("expected 30 cycles, actual: %d", cpu.Cycles)
+	}
+}
+
+func TestDisplayWaitEndsFrame(t *testing.T) {
+	program := []byte{
+		0xD0, 0x01, //0x200 - draw
+		0x12, 0x00, //0x202 - jump to 0x200
+	}
+
+	cpu := chip8.NewCPU(nil)
+	cpu.Quirks = chip8.VIP
+	cpu.LoadData(0x200, program)
+	cpu.Frame()
+	if cpu.Cycles != 1 {
+		t.Errorf("VIP: %d cycles in first frame", cpu.Cycles)
+	}
+
+	cpu = chip8.NewCPU(nil)
+	cpu.Quirks = chip8.CHIP48
+	cpu.LoadData(0x200, program)
+	cpu.Frame()
+	if cpu.Cycles <= 1 {
+		t.Errorf("CHIP48: %d cycles in first frame", cpu.Cycles)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !sdl
+// +build !sdl
+
+package chip8
+
+func NewSound() (Sound, error) {
+	return nil, ErrNoSDL
+}
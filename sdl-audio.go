@@ -0,0 +1,67 @@
+//go:build sdl
+// +build sdl
+
+package chip8
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const sdlSampleRate = 44100
+
+type sdlSound struct {
+	device sdl.AudioDeviceID
+	tone   []byte
+}
+
+// NewSound opens the default SDL audio device and plays a square wave while the sound timer
+// runs. The returned Sound is an io.Closer. It is only available when built with the sdl tag.
+func NewSound() (Sound, error) {
+	if err := sdl.InitSubSystem(sdl.INIT_AUDIO); err != nil {
+		return nil, err
+	}
+
+	spec := &sdl.AudioSpec{
+		Freq:     sdlSampleRate,
+		Format:   sdl.AUDIO_U8,
+		Channels: 1,
+		Samples:  1024,
+	}
+	device, err := sdl.OpenAudioDevice("", false, spec, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	//the sound timer runs for at most 255 frames, so queue enough tone to cover it
+	samples := sdlSampleRate * 256 / TimerFrequency
+	tone := make([]byte, samples)
+	period := sdlSampleRate / BeepFrequency
+	for i := range tone {
+		if i%period < period/2 {
+			tone[i] = 0xA0
+		} else {
+			tone[i] = 0x60
+		}
+	}
+
+	return &sdlSound{
+		device: device,
+		tone:   tone,
+	}, nil
+}
+
+func (s *sdlSound) Start(frame uint64) {
+	sdl.ClearQueuedAudio(s.device)
+	sdl.QueueAudio(s.device, s.tone)
+	sdl.PauseAudioDevice(s.device, false)
+}
+
+func (s *sdlSound) Stop(frame uint64) {
+	sdl.PauseAudioDevice(s.device, true)
+	sdl.ClearQueuedAudio(s.device)
+}
+
+func (s *sdlSound) Close() error {
+	sdl.CloseAudioDevice(s.device)
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !sdl
+// +build !sdl
+
+package chip8
+
+// HaveSDL reports whether the package was built with the sdl tag, so that NewDisplay and
+// NewSound can work
+const HaveSDL = false
+
+func NewDisplay(keypad Keypad, keymap KeyMap, scale int) (Display, error) {
+	return nil, ErrNoSDL
+}
@@ -1,97 +1,151 @@
+//go:build sdl
+// +build sdl
+
 package chip8
 
 import (
-	"fmt"
-
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+// HaveSDL reports whether the package was built with the sdl tag, so that NewDisplay and
+// NewSound can work
+const HaveSDL = true
+
 type sdlDisplay struct {
-	window *sdl.Window
+	window   *sdl.Window
+	renderer *sdl.Renderer
+	scale    int32
+	keypad   Keypad
+	keys     map[sdl.Keycode]byte
+	closed   bool
+
+	//the window was uncovered and needs drawing even if the framebuffer has not changed
+	exposed bool
+
+	//hotkeys pressed since the last call to Hotkeys
+	hotkeys []Hotkey
+}
+
+// hotkeyBindings are the keys of the emulator commands
+var hotkeyBindings = map[sdl.Keycode]Hotkey{
+	sdl.K_F5:        HotkeySaveState,
+	sdl.K_F9:        HotkeyLoadState,
+	sdl.K_BACKSPACE: HotkeyRewind,
 }
 
-func NewDisplay() (Display, error) {
+// NewDisplay opens an SDL window. It is only available when built with the sdl tag. Keyboard input is translated through keymap into keypad presses.
+// Each high resolution pixel is drawn scale screen pixels wide.
+func NewDisplay(keypad Keypad, keymap KeyMap, scale int) (Display, error) {
+	if scale < 1 {
+		scale = DefaultScale
+	}
 	err := sdl.Init(sdl.INIT_EVERYTHING)
 	if err != nil {
 		return nil, err
 	}
 
-	width := int32(64)
-	height := int32(32)
+	//large enough for SUPER-CHIP high resolution; low resolution pixels are doubled
+	width := int32(HiResWidth * scale)
+	height := int32(HiResHeight * scale)
 	window, err := sdl.CreateWindow("chip8", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, width, height, sdl.WINDOW_SHOWN)
 	if err != nil {
 		return nil, err
 	}
+	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	if err != nil {
+		window.Destroy()
+		return nil, err
+	}
 
 	display := &sdlDisplay{
-		window: window,
+		window:   window,
+		renderer: renderer,
+		scale:    int32(scale),
+		keypad:   keypad,
+		keys:     map[sdl.Keycode]byte{},
+		exposed:  true,
+	}
+	for name, key := range keymap {
+		if code := sdl.GetKeyFromName(name); code != sdl.K_UNKNOWN {
+			display.keys[code] = key
+		}
 	}
 
 	return display, nil
 }
 
-func (d *sdlDisplay) Draw(vram []byte, dataType int) {
-	switch dataType {
-	case PIXELS_MONOCHROME:
-		d.drawMonochrome(vram)
+func (d *sdlDisplay) Draw(fb *Framebuffer) {
+	if fb.Dirty() || d.exposed {
+		d.drawPixels(fb)
+		d.exposed = false
 	}
 	d.CheckEvents()
 }
 
 func (d *sdlDisplay) CheckEvents() {
-	sdl.PollEvent()
-	//todo:use events for input
-}
-
-func (d *sdlDisplay) drawMonochrome(vram []byte) {
-	r, err := d.window.GetRenderer()
-	r.Clear()
-
-	if err != nil {
-		return
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch e := event.(type) {
+		case *sdl.QuitEvent:
+			d.closed = true
+		case *sdl.WindowEvent:
+			if e.Event == sdl.WINDOWEVENT_EXPOSED {
+				d.exposed = true
+			}
+		case *sdl.KeyboardEvent:
+			if e.Keysym.Sym == sdl.K_ESCAPE {
+				d.closed = true
+				continue
+			}
+			if hotkey, ok := hotkeyBindings[e.Keysym.Sym]; ok {
+				if e.Type == sdl.KEYDOWN && (e.Repeat == 0 || hotkey == HotkeyRewind) {
+					d.hotkeys = append(d.hotkeys, hotkey)
+				}
+				continue
+			}
+			if key, ok := d.keys[e.Keysym.Sym]; ok && d.keypad != nil {
+				d.keypad.SetPressed(key, e.Type == sdl.KEYDOWN)
+			}
+		}
 	}
+}
 
-	pixelData := decodeColourFromMonochromeBitmap(vram)
-
-	for x := 0; x < 63; x++ {
-		for y := 0; y < 31; y++ {
+func (d *sdlDisplay) Hotkeys() []Hotkey {
+	hotkeys := d.hotkeys
+	d.hotkeys = nil
+	return hotkeys
+}
 
-			colour := pixelData[(y*64)+x]
-			r.SetDrawColor(colour.R, colour.G, colour.B, colour.A)
-			r.DrawPoint(int32(x), int32(y))
-		}
-	}
-	d.window.UpdateSurface()
+func (d *sdlDisplay) Closed() bool {
+	return d.closed
 }
 
-func decodeColourFromMonochromeBitmap(vram []byte) []sdl.Color {
-
-	pixels := make([]sdl.Color, 64*32)
-	counter := 0
-	for row := 0; row < 32; row++ {
-		rowInt := vram[row]
-		rowBinary := fmt.Sprintf("%064b", rowInt)
-
-		for _, val := range []rune(rowBinary) {
-			switch string(val) {
-			case "0":
-				pixels[counter] = sdl.Color{R: 0, G: 0, B: 0, A: 255}
-				break
-			case "1":
-				pixels[counter] = sdl.Color{R: 255, G: 255, B: 255, A: 255}
-				break
-			}
+func (d *sdlDisplay) drawPixels(fb *Framebuffer) {
+	r := d.renderer
+	background := Palette[0]
+	r.SetDrawColor(background.R, background.G, background.B, background.A)
+	r.Clear()
 
-			counter += 1
+	size := d.scale * int32(HiResWidth/fb.Width())
+	for colour := 1; colour < len(Palette); colour++ {
+		var rects []sdl.Rect
+		for y := 0; y < fb.Height(); y++ {
+			for x, pixel := range fb.Row(y) {
+				if int(pixel) == colour {
+					rects = append(rects, sdl.Rect{X: int32(x) * size, Y: int32(y) * size, W: size, H: size})
+				}
+			}
+		}
+		if len(rects) > 0 {
+			c := Palette[colour]
+			r.SetDrawColor(c.R, c.G, c.B, c.A)
+			r.FillRects(rects)
 		}
 	}
-
-	return pixels
+	r.Present()
 }
 
 func (d *sdlDisplay) Close() {
-
+	d.renderer.Destroy()
 	d.window.Destroy()
 	sdl.Quit()
-
 }
@@ -0,0 +1,82 @@
+package chip8
+
+import "sync"
+
+// BeepFrequency is the pitch in Hz of the beeper while the sound timer is running
+const BeepFrequency = 440
+
+// Sound is signalled when the sound timer starts and stops the beeper.
+// frame is the number of 60Hz frames emulated when the change happened.
+type Sound interface {
+	Start(frame uint64)
+	Stop(frame uint64)
+}
+
+// PatternSound is a Sound that can also play the XO-CHIP audio pattern buffer. Until
+// SetPattern is first called it plays the plain beeper.
+type PatternSound interface {
+	Sound
+	SetPattern(frame uint64, pattern [16]byte, pitch byte)
+}
+
+// updatePattern passes the audio pattern buffer and pitch to Sound after F002 or FX3A
+func (c *CPU) updatePattern() {
+	if s, ok := c.Sound.(PatternSound); ok {
+		s.SetPattern(c.Frames, c.AudioPattern, c.Pitch)
+	}
+}
+
+// updateSound signals Sound when ST moves between zero and non-zero
+func (c *CPU) updateSound() {
+	on := c.ST > 0
+	if on == c.beeping {
+		return
+	}
+	c.beeping = on
+	if c.Sound == nil {
+		return
+	}
+	if on {
+		c.Sound.Start(c.Frames)
+	} else {
+		c.Sound.Stop(c.Frames)
+	}
+}
+
+// Beep is a span of frames during which the beeper sounded.
+// Stop is the frame the beeper stopped in, or zero while it is still sounding.
+type Beep struct {
+	Start uint64
+	Stop  uint64
+}
+
+// RecordingSound is a silent Sound that logs when the beeper starts and stops
+type RecordingSound struct {
+	mu    sync.Mutex
+	beeps []Beep
+}
+
+func NewRecordingSound() *RecordingSound {
+	return &RecordingSound{}
+}
+
+func (s *RecordingSound) Start(frame uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.beeps = append(s.beeps, Beep{Start: frame})
+}
+
+func (s *RecordingSound) Stop(frame uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.beeps) > 0 {
+		s.beeps[len(s.beeps)-1].Stop = frame
+	}
+}
+
+// Beeps returns the beeps recorded so far, oldest first
+func (s *RecordingSound) Beeps() []Beep {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Beep(nil), s.beeps...)
+}
@@ -0,0 +1,52 @@
+package chip8_test
+
+import (
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestSoundTimerBeeps(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	sound := chip8.NewRecordingSound()
+	cpu.Sound = sound
+	cpu.LoadData(0x200, []byte{
+		0x60, 0x03, //0x200 - set v0 to 3
+		0xF0, 0x18, //0x202 - set ST to v0
+		0x12, 0x04, //0x204 - jump to self
+	})
+
+	for i := 0; i < 2; i++ {
+		cpu.Frame()
+	}
+	beeps := sound.Beeps()
+	if len(beeps) != 1 || beeps[0].Start != 0 || beeps[0].Stop != 0 {
+		t.Errorf("while sounding: %v", beeps)
+	}
+
+	for i := 0; i < 10; i++ {
+		cpu.Frame()
+	}
+	beeps = sound.Beeps()
+	if len(beeps) != 1 || beeps[0].Start != 0 || beeps[0].Stop != 3 {
+		t.Errorf("expected one beep from frame 0 to 3: %v", beeps)
+	}
+}
+
+func TestSoundTimerRestart(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	sound := chip8.NewRecordingSound()
+	cpu.Sound = sound
+	cpu.V[0] = 1
+
+	cpu.ExecuteOp(0xF018)
+	cpu.Frame()
+	cpu.ExecuteOp(0xF018)
+	cpu.ExecuteOp(0xF018)
+	cpu.Frame()
+
+	beeps := sound.Beeps()
+	if len(beeps) != 2 || beeps[1].Start != 1 || beeps[1].Stop != 2 {
+		t.Errorf("expected two beeps: %v", beeps)
+	}
+}
@@ -0,0 +1,214 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TerminalMode selects how framebuffer pixels are packed into terminal characters
+type TerminalMode int
+
+const (
+	//TerminalHalfBlocks draws two pixels per character with '▀', keeping XO-CHIP colours
+	TerminalHalfBlocks TerminalMode = iota
+
+	//TerminalBraille draws 2x4 pixels per braille character, in monochrome
+	TerminalBraille
+)
+
+// TerminalKeyHold is how long a key stays pressed after the terminal last sent it.
+// Terminals only report key presses, so releases are inferred once autorepeat stops.
+const TerminalKeyHold = 150 * time.Millisecond
+
+// TerminalDisplay renders the framebuffer into an ANSI terminal and reads keypad input from it.
+// Ctrl-C closes the display.
+type TerminalDisplay struct {
+	out    *bufio.Writer
+	mode   TerminalMode
+	keypad Keypad
+	keymap KeyMap
+
+	//characters on screen, to redraw only the cells that change
+	cells  []string
+	width  int
+	height int
+
+	//stty settings to restore on Close, when the input was put in raw mode
+	tty     *os.File
+	restore string
+
+	//keys the terminal sent, applied to the keypad between frames so that a frame sees the
+	//same keys from start to end, as recorded movies need
+	mu       sync.Mutex
+	lastSeen [KeyCount]time.Time
+	latched  [KeyCount]bool
+	closed   bool
+}
+
+// NewTerminalDisplay draws to out and reads keys from in. When in is a terminal it is put
+// into raw mode until Close is called.
+func NewTerminalDisplay(in io.Reader, out io.Writer, mode TerminalMode, keypad Keypad, keymap KeyMap) (*TerminalDisplay, error) {
+	d := &TerminalDisplay{
+		out:    bufio.NewWriter(out),
+		mode:   mode,
+		keypad: keypad,
+		keymap: keymap,
+	}
+
+	if f, ok := in.(*os.File); ok {
+		if state, err := stty(f, "-g"); err == nil {
+			if _, err := stty(f, "raw", "-echo"); err != nil {
+				return nil, fmt.Errorf("could not put terminal in raw mode: %s", err)
+			}
+			d.tty = f
+			d.restore = strings.TrimSpace(state)
+		}
+	}
+
+	//hide the cursor and clear the screen
+	d.out.WriteString("\x1b[?25l\x1b[2J")
+	d.out.Flush()
+
+	go d.readKeys(in)
+	return d, nil
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func (d *TerminalDisplay) readKeys(in io.Reader) {
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		d.mu.Lock()
+		for _, b := range buf[:n] {
+			if b == 0x03 {
+				//Ctrl-C
+				d.closed = true
+				continue
+			}
+			if key, ok := d.keymap.Lookup(string(rune(b))); ok {
+				d.lastSeen[key] = time.Now()
+			}
+		}
+		d.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// latchKeys presses the keys the terminal has sent since the last frame, and lets go of keys
+// it has stopped repeating. Every key sent is held for at least one frame.
+func (d *TerminalDisplay) latchKeys() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	for key, seen := range d.lastSeen {
+		switch {
+		case seen.IsZero():
+			continue
+		case !d.latched[key]:
+			d.latched[key] = true
+			if d.keypad != nil {
+				d.keypad.SetPressed(byte(key), true)
+			}
+		case now.Sub(seen) > TerminalKeyHold:
+			d.lastSeen[key], d.latched[key] = time.Time{}, false
+			if d.keypad != nil {
+				d.keypad.SetPressed(byte(key), false)
+			}
+		}
+	}
+}
+
+func (d *TerminalDisplay) Draw(fb *Framebuffer) {
+	d.latchKeys()
+	if !fb.Dirty() && d.cells != nil {
+		return
+	}
+
+	width, height := fb.Width(), (fb.Height()+1)/2
+	if d.mode == TerminalBraille {
+		width, height = (fb.Width()+1)/2, (fb.Height()+3)/4
+	}
+	if width != d.width || height != d.height {
+		//resolution changed: start again from a blank screen
+		d.width, d.height = width, height
+		d.cells = make([]string, width*height)
+		d.out.WriteString("\x1b[0m\x1b[2J")
+	}
+
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			var cell string
+			if d.mode == TerminalBraille {
+				cell = brailleCell(fb, col*2, row*4)
+			} else {
+				cell = halfBlockCell(fb, col, row*2)
+			}
+			if d.cells[row*width+col] != cell {
+				d.cells[row*width+col] = cell
+				fmt.Fprintf(d.out, "\x1b[%d;%dH%s", row+1, col+1, cell)
+			}
+		}
+	}
+	d.out.Flush()
+}
+
+// halfBlockCell renders pixels (x,y) and (x,y+1) as an upper half block coloured by the top
+// pixel on a background coloured by the bottom one
+func halfBlockCell(fb *Framebuffer, x, y int) string {
+	top := Palette[fb.Colour(x, y)]
+	bottom := Palette[fb.Colour(x, y+1)]
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%d;48;2;%d;%d;%dm▀", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+}
+
+// brailleDots maps a pixel offset within a 2x4 braille cell to its dot bit
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+func brailleCell(fb *Framebuffer, x, y int) string {
+	r := rune(0x2800)
+	for dy := 0; dy < 4; dy++ {
+		for dx := 0; dx < 2; dx++ {
+			if fb.Pixel(x+dx, y+dy) {
+				r |= brailleDots[dy][dx]
+			}
+		}
+	}
+	return string(r)
+}
+
+func (d *TerminalDisplay) Closed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closed
+}
+
+// Close restores the terminal
+func (d *TerminalDisplay) Close() {
+	fmt.Fprintf(d.out, "\x1b[0m\x1b[%d;1H\x1b[?25h\r\n", d.height+1)
+	d.out.Flush()
+	if d.tty != nil {
+		stty(d.tty, d.restore)
+		d.tty = nil
+	}
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+}
@@ -0,0 +1,100 @@
+package chip8_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestTerminalDisplayRedrawsChangedCells(t *testing.T) {
+	var out bytes.Buffer
+	d, err := chip8.NewTerminalDisplay(strings.NewReader(""), &out, chip8.TerminalHalfBlocks, nil, chip8.DefaultKeyMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+	d.Draw(fb)
+	fb.MarkClean()
+	if strings.Count(out.String(), "▀") != chip8.ScreenWidth*chip8.ScreenHeight/2 {
+		t.Log("first frame did not draw every cell")
+		t.Fail()
+	}
+
+	out.Reset()
+	fb.SetPixel(5, 3, true)
+	d.Draw(fb)
+	if strings.Count(out.String(), "▀") != 1 || !strings.Contains(out.String(), "\x1b[2;6H") {
+		t.Errorf("expected a single cell at row 2, column 6: %q", out.String())
+	}
+
+	out.Reset()
+	fb.MarkClean()
+	d.Draw(fb)
+	if out.Len() != 0 {
+		t.Errorf("unchanged frame redrawn: %q", out.String())
+	}
+}
+
+func TestTerminalDisplayBraille(t *testing.T) {
+	var out bytes.Buffer
+	d, err := chip8.NewTerminalDisplay(strings.NewReader(""), &out, chip8.TerminalBraille, nil, chip8.DefaultKeyMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+	fb.SetPixel(0, 0, true)
+	fb.SetPixel(1, 3, true)
+	d.Draw(fb)
+	if !strings.Contains(out.String(), "\x1b[1;1H⢁") {
+		t.Errorf("braille cell not drawn: %q", out.String()[:40])
+	}
+}
+
+func TestTerminalDisplayKeys(t *testing.T) {
+	r, w := io.Pipe()
+	keypad := chip8.NewKeypad()
+	d, err := chip8.NewTerminalDisplay(r, &bytes.Buffer{}, chip8.TerminalHalfBlocks, keypad, chip8.DefaultKeyMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fb := chip8.NewFramebuffer(chip8.ScreenWidth, chip8.ScreenHeight)
+	w.Write([]byte("w"))
+	//keys only reach the keypad between frames, when the display is drawn
+	time.Sleep(10 * time.Millisecond)
+	if keypad.Pressed(0x5) {
+		t.Error("key pressed part way through a frame")
+	}
+	if !waitFor(func() bool { d.Draw(fb); return keypad.Pressed(0x5) }) {
+		t.Fatal("key not pressed")
+	}
+
+	time.Sleep(chip8.TerminalKeyHold + 10*time.Millisecond)
+	d.Draw(fb)
+	if keypad.Pressed(0x5) {
+		t.Log("key not released once the terminal stopped sending it")
+		t.Fail()
+	}
+
+	w.Write([]byte{0x03})
+	if !waitFor(d.Closed) {
+		t.Log("ctrl-c did not close the display")
+		t.Fail()
+	}
+	w.Close()
+}
+
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
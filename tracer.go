@@ -0,0 +1,128 @@
+package chip8
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TraceEvent describes one executed instruction
+type TraceEvent struct {
+	//instructions executed before this one
+	Cycle uint64 `json:"cycle"`
+
+	//address and value of the instruction
+	PC     uint16 `json:"pc"`
+	Opcode uint16 `json:"opcode"`
+
+	//the decoded instruction, whose String is its disassembly
+	Instruction Instruction `json:"instruction"`
+
+	//registers the instruction changed, in the order V0-VF, I, SP, DT, ST
+	Changes []RegisterChange `json:"changes,omitempty"`
+}
+
+// RegisterChange is the value of a register before and after an instruction
+type RegisterChange struct {
+	Register string `json:"register"`
+	Old      uint16 `json:"old"`
+	New      uint16 `json:"new"`
+}
+
+// Tracer receives an event for every instruction the CPU executes successfully
+type Tracer interface {
+	Trace(event TraceEvent)
+}
+
+// registerNames names the registers in the order registers() returns them
+var registerNames = [...]string{
+	"V0", "V1", "V2", "V3", "V4", "V5", "V6", "V7",
+	"V8", "V9", "VA", "VB", "VC", "VD", "VE", "VF",
+	"I", "SP", "DT", "ST",
+}
+
+// registers snapshots the registers an instruction can change, other than PC
+func (c *CPU) registers() [len(registerNames)]uint16 {
+	var regs [len(registerNames)]uint16
+	for i, v := range c.V {
+		regs[i] = uint16(v)
+	}
+	regs[16] = c.I
+	regs[17] = uint16(c.SP)
+	regs[18] = uint16(c.DT)
+	regs[19] = uint16(c.ST)
+	return regs
+}
+
+// trace sends the event for the instruction at pc to the Tracer
+func (c *CPU) trace(pc, opCode uint16, before [len(registerNames)]uint16) {
+	event := TraceEvent{
+		Cycle:       c.Cycles,
+		PC:          pc,
+		Opcode:      opCode,
+		Instruction: DecodeAt(c.Memory, int(pc), c.Platform),
+	}
+	after := c.registers()
+	for i := range after {
+		if after[i] != before[i] {
+			event.Changes = append(event.Changes, RegisterChange{
+				Register: registerNames[i],
+				Old:      before[i],
+				New:      after[i],
+			})
+		}
+	}
+	c.Tracer.Trace(event)
+}
+
+// NopTracer discards every event
+type NopTracer struct{}
+
+func (NopTracer) Trace(event TraceEvent) {}
+
+// TextTracer writes one human readable line per instruction, e.g.
+//
+//	0000012 0x204 6A02 SET vA,0x2        VA=0x0->0x2
+type TextTracer struct {
+	w *bufio.Writer
+}
+
+func NewTextTracer(w io.Writer) *TextTracer {
+	return &TextTracer{w: bufio.NewWriter(w)}
+}
+
+func (t *TextTracer) Trace(event TraceEvent) {
+	changes := make([]string, len(event.Changes))
+	for i, change := range event.Changes {
+		changes[i] = fmt.Sprintf("%s=%#x->%#x", change.Register, change.Old, change.New)
+	}
+	line := fmt.Sprintf("%07d %#x %04X %-16s %s", event.Cycle, event.PC, event.Opcode, event.Instruction.String(), strings.Join(changes, " "))
+	t.w.WriteString(strings.TrimRight(line, " ") + "\n")
+}
+
+// Flush writes any buffered lines
+func (t *TextTracer) Flush() error {
+	return t.w.Flush()
+}
+
+// JSONTracer writes each event as a JSON object on its own line
+type JSONTracer struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	buf := bufio.NewWriter(w)
+	return &JSONTracer{w: buf, enc: json.NewEncoder(buf)}
+}
+
+func (t *JSONTracer) Trace(event TraceEvent) {
+	t.enc.Encode(event)
+}
+
+// Flush writes any buffered events
+func (t *JSONTracer) Flush() error {
+	return t.w.Flush()
+}
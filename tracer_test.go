@@ -0,0 +1,80 @@
+package chip8_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+type eventLog []chip8.TraceEvent
+
+func (l *eventLog) Trace(event chip8.TraceEvent) {
+	*l = append(*l, event)
+}
+
+func TestTracerEvents(t *testing.T) {
+	cpu := chip8.NewCPU(nil)
+	events := &eventLog{}
+	cpu.Tracer = events
+	cpu.LoadData(0x200, []byte{
+		0x6A, 0x02, //0x200 - set vA to 2
+		0x12, 0x00, //0x202 - jump to 0x200
+	})
+	cpu.Step()
+	cpu.Step()
+	cpu.Step()
+
+	if len(*events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(*events))
+	}
+	first := (*events)[0]
+	if first.PC != 0x200 || first.Opcode != 0x6A02 || first.Instruction.String() != "SET vA,0x2" || first.Cycle != 0 {
+		t.Errorf("unexpected event %+v", first)
+	}
+	if len(first.Changes) != 1 || first.Changes[0] != (chip8.RegisterChange{Register: "VA", Old: 0, New: 2}) {
+		t.Errorf("unexpected changes %+v", first.Changes)
+	}
+	if changes := (*events)[2].Changes; len(changes) != 0 {
+		t.Errorf("setting vA to the same value reported changes %+v", changes)
+	}
+}
+
+func TestTextAndJSONTracers(t *testing.T) {
+	event := chip8.TraceEvent{
+		Cycle:       12,
+		PC:          0x204,
+		Opcode:      0x6A02,
+		Instruction: chip8.Decode(0x6A02, chip8.PlatformCHIP8),
+		Changes:     []chip8.RegisterChange{{Register: "VA", Old: 0, New: 2}},
+	}
+
+	var text bytes.Buffer
+	tracer := chip8.NewTextTracer(&text)
+	tracer.Trace(event)
+	tracer.Flush()
+	if line := text.String(); !strings.Contains(line, "0x204 6A02 SET vA,0x2") || !strings.HasSuffix(line, "VA=0x0->0x2\n") {
+		t.Errorf("unexpected text trace %q", line)
+	}
+
+	var lines bytes.Buffer
+	jsonTracer := chip8.NewJSONTracer(&lines)
+	jsonTracer.Trace(event)
+	jsonTracer.Trace(event)
+	jsonTracer.Flush()
+	decoder := json.NewDecoder(&lines)
+	for i := 0; i < 2; i++ {
+		var decoded chip8.TraceEvent
+		if err := decoder.Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.PC != event.PC || decoded.Instruction != event.Instruction || len(decoded.Changes) != 1 {
+			t.Errorf("unexpected JSON trace %+v", decoded)
+		}
+		if inst := decoded.Instruction; inst.Op != chip8.OpSetImm || inst.X != 0xA || inst.NN != 2 {
+			t.Errorf("unexpected decoded instruction %+v", inst)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package chip8
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+)
+
+// DefaultSampleRate is the sample rate in Hz WAV recordings are rendered at unless told otherwise
+const DefaultSampleRate = 44100
+
+// ErrSampleRate is returned by NewWAVRecorder for a sample rate that is not positive
+var ErrSampleRate = errors.New("chip8: sample rate must be positive")
+
+// levels of the 8-bit unsigned samples written to the WAV file
+const (
+	wavSilence = 0x80
+	wavHigh    = 0xA0
+	wavLow     = 0x60
+)
+
+// WAVRecorder is a Sound that renders the beeper, and in XO-CHIP mode the audio pattern
+// buffer, into an 8-bit mono WAV file. Samples are placed by emulated frame rather than wall
+// clock time, so a recording is the same however fast the emulator ran.
+// Call Finish once the program has stopped to write the file.
+type WAVRecorder struct {
+	mu         sync.Mutex
+	w          io.Writer
+	sampleRate int
+	samples    []byte
+
+	//frame the samples have been rendered up to
+	frame uint64
+	on    bool
+
+	//XO-CHIP pattern playback, set once the program loads a pattern or pitch
+	patterned bool
+	pattern   [16]byte
+	pitch     byte
+
+	//position within the current square wave period or pattern, in cycles or bits
+	phase float64
+}
+
+// NewWAVRecorder records to w at sampleRate samples per second
+func NewWAVRecorder(w io.Writer, sampleRate int) (*WAVRecorder, error) {
+	if sampleRate <= 0 {
+		return nil, ErrSampleRate
+	}
+	return &WAVRecorder{
+		w:          w,
+		sampleRate: sampleRate,
+		pitch:      DefaultPitch,
+	}, nil
+}
+
+func (r *WAVRecorder) Start(frame uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.render(frame)
+	r.on = true
+}
+
+func (r *WAVRecorder) Stop(frame uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.render(frame)
+	r.on = false
+}
+
+func (r *WAVRecorder) SetPattern(frame uint64, pattern [16]byte, pitch byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.render(frame)
+	r.patterned = true
+	r.pattern = pattern
+	r.pitch = pitch
+}
+
+// render appends samples up to the start of frame using the current state
+func (r *WAVRecorder) render(frame uint64) {
+	if frame <= r.frame {
+		return
+	}
+	end := int(frame * uint64(r.sampleRate) / TimerFrequency)
+
+	if !r.on {
+		for len(r.samples) < end {
+			r.samples = append(r.samples, wavSilence)
+		}
+		r.frame = frame
+		return
+	}
+
+	if !r.patterned {
+		step := float64(BeepFrequency) / float64(r.sampleRate)
+		for len(r.samples) < end {
+			if r.phase < 0.5 {
+				r.samples = append(r.samples, wavHigh)
+			} else {
+				r.samples = append(r.samples, wavLow)
+			}
+			r.phase = math.Mod(r.phase+step, 1)
+		}
+		r.frame = frame
+		return
+	}
+
+	//the 128 bit pattern plays at 4000*2^((pitch-64)/48) bits per second
+	step := 4000 * math.Pow(2, (float64(r.pitch)-64)/48) / float64(r.sampleRate)
+	for len(r.samples) < end {
+		bit := int(r.phase)
+		if r.pattern[bit/8]&(0x80>>uint(bit%8)) != 0 {
+			r.samples = append(r.samples, wavHigh)
+		} else {
+			r.samples = append(r.samples, wavLow)
+		}
+		r.phase = math.Mod(r.phase+step, 128)
+	}
+	r.frame = frame
+}
+
+// Samples returns the 8-bit unsigned samples rendered so far
+func (r *WAVRecorder) Samples() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte(nil), r.samples...)
+}
+
+// Finish renders the audio up to frame and writes the WAV file
+func (r *WAVRecorder) Finish(frame uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.render(frame)
+
+	//RIFF chunks are padded to an even length, and the padding counts towards the file's size
+	pad := len(r.samples) % 2
+
+	header := struct {
+		RIFF          [4]byte
+		ChunkSize     uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		AudioFormat   uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		ChunkSize:     uint32(36 + len(r.samples) + pad),
+		WAVE:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		AudioFormat:   1, //PCM
+		Channels:      1,
+		SampleRate:    uint32(r.sampleRate),
+		ByteRate:      uint32(r.sampleRate),
+		BlockAlign:    1,
+		BitsPerSample: 8,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      uint32(len(r.samples)),
+	}
+	if err := binary.Write(r.w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	if _, err := r.w.Write(r.samples); err != nil {
+		return err
+	}
+	if pad == 1 {
+		_, err := r.w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package chip8_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/alisdairrankine/chip8"
+)
+
+func TestWAVRecorderBeep(t *testing.T) {
+	var out bytes.Buffer
+	recorder, err := chip8.NewWAVRecorder(&out, 6000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := chip8.NewCPU(nil)
+	cpu.Sound = recorder
+	cpu.LoadData(0x200, []byte{
+		0x60, 0x02, //0x200 - set v0 to 2
+		0xF0, 0x18, //0x202 - set ST to v0
+		0x12, 0x04, //0x204 - jump to self
+	})
+	for i := 0; i < 4; i++ {
+		cpu.Frame()
+	}
+	if err := recorder.Finish(cpu.Frames); err != nil {
+		t.Fatal(err)
+	}
+
+	wav := out.Bytes()
+	if string(wav[0:4]) != "RIFF" || string(wav[8:12]) != "WAVE" || string(wav[36:40]) != "data" {
+		t.Fatalf("bad header: %q", wav[:44])
+	}
+	if rate := binary.LittleEndian.Uint32(wav[24:28]); rate != 6000 {
+		t.Errorf("expected sample rate 6000, got %d", rate)
+	}
+
+	//100 samples per frame: two frames of tone then two of silence
+	samples := wav[44:]
+	if len(samples) != 400 {
+		t.Fatalf("expected 400 samples, got %d", len(samples))
+	}
+	for i, s := range samples {
+		if i < 200 && s == 0x80 {
+			t.Errorf("sample %d is silent during the beep", i)
+			break
+		}
+		if i >= 200 && s != 0x80 {
+			t.Errorf("sample %d is not silent after the beep", i)
+			break
+		}
+	}
+}
+
+func TestWAVRecorderPattern(t *testing.T) {
+	var out bytes.Buffer
+	recorder, err := chip8.NewWAVRecorder(&out, 4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := chip8.NewCPU(nil)
+	cpu.SetPlatform(chip8.PlatformXOCHIP)
+	cpu.Sound = recorder
+
+	//at the default pitch the pattern plays one bit per sample at 4000Hz
+	pattern := []byte{0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00}
+	cpu.LoadData(0x300, pattern)
+	cpu.I = 0x300
+	cpu.ExecuteOp(0xF002)
+	cpu.V[0] = 1
+	cpu.ExecuteOp(0xF018)
+	cpu.Frame()
+
+	samples := recorder.Samples()
+	for i := 0; i < 16; i++ {
+		expected := byte(0x60)
+		if (i/8)%2 == 0 {
+			expected = 0xA0
+		}
+		if samples[i] != expected {
+			t.Errorf("sample %d: expected %#x, got %#x", i, expected, samples[i])
+		}
+	}
+}
+
+func TestWAVRecorderOddLength(t *testing.T) {
+	//a frame at 6030Hz is 100.5 samples, so 3 frames are 301
+	var out bytes.Buffer
+	recorder, err := chip8.NewWAVRecorder(&out, 6030)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Finish(3); err != nil {
+		t.Fatal(err)
+	}
+
+	wav := out.Bytes()
+	if size := binary.LittleEndian.Uint32(wav[40:44]); size != 301 {
+		t.Fatalf("expected 301 samples, got %d", size)
+	}
+	if len(wav) != 44+302 {
+		t.Errorf("data is not padded to an even length: %d bytes", len(wav))
+	}
+	if size := binary.LittleEndian.Uint32(wav[4:8]); int(size) != len(wav)-8 {
+		t.Errorf("RIFF chunk size %d does not match the %d bytes after it", size, len(wav)-8)
+	}
+}
+
+func TestWAVRecorderSampleRate(t *testing.T) {
+	for _, rate := range []int{0, -44100} {
+		if _, err := chip8.NewWAVRecorder(&bytes.Buffer{}, rate); err != chip8.ErrSampleRate {
+			t.Errorf("sample rate %d: expected ErrSampleRate, got %v", rate, err)
+		}
+	}
+}